@@ -0,0 +1,277 @@
+// Command rutil-env loads .env files with the same parsing as fileops.EnvFromFile, via fileops.ParseEnvFile.
+//
+//	rutil-env run [-expand] [-f FILE]... [--] COMMAND [ARGS...]
+//	rutil-env print [-expand] [-f FILE]...
+//	rutil-env check [-expand] [-example FILE] [-f FILE]...
+//	rutil-env diff [-expand] FILE_A FILE_B
+//
+// FILE defaults to .env and may be repeated, later files overriding earlier ones.
+// With -expand, variables in values are expanded as fileops.EnvOptions describes.
+// Exit status is that of COMMAND for run, otherwise 0 on success, 1 when variables are
+// missing or the files differ, and 2 on usage or read errors
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/go-rutil/rutil/fileops"
+)
+
+const (
+	exitOK       = 0
+	exitNegative = 1 // missing variables or different files
+	exitError    = 2
+)
+
+const usage = `usage: rutil-env <command> [arguments]
+
+commands:
+  run [-f FILE]... [--] COMMAND [ARGS...]   run COMMAND with the variables of the files added
+  print [-f FILE]...                        print the variables as shell export statements
+  check [-example FILE] [-f FILE]...        report variables of the example file that are not set
+  diff FILE_A FILE_B                        show the differences between two env files
+
+Each command accepts -expand to expand $VAR and ${VAR} references in values.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command in args, returning the exit status
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		_, _ = fmt.Fprint(stderr, usage)
+		return exitError
+	}
+
+	switch args[0] {
+	case "run":
+		return runRun(args[1:], stdin, stdout, stderr)
+	case "print":
+		return runPrint(args[1:], stdout, stderr)
+	case "check":
+		return runCheck(args[1:], stdout, stderr)
+	case "diff":
+		return runDiff(args[1:], stdout, stderr)
+	case "help", "-h", "-help":
+	default:
+		_, _ = fmt.Fprintf(stderr, "rutil-env: unknown command %q\n", args[0])
+	}
+	_, _ = fmt.Fprint(stderr, usage)
+	return exitError
+}
+
+func runRun(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs, load := newFlagSet("run", stderr)
+	if err := fs.Parse(args); err != nil || fs.NArg() == 0 {
+		return usageError(stderr, "run [-expand] [-f FILE]... [--] COMMAND [ARGS...]")
+	}
+
+	vars, err := loadFiles(load.files.list(), load.opts, stderr)
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	env := fileops.NewEnvBuilder(true)
+	for key, val := range vars {
+		env.Set(key, val)
+	}
+
+	cmd := exec.Command(fs.Arg(0), fs.Args()[1:]...)
+	cmd.Env, cmd.Stdin, cmd.Stdout, cmd.Stderr = env.Environ(), stdin, stdout, stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		return fail(stderr, err)
+	}
+	return exitOK
+}
+
+func runPrint(args []string, stdout, stderr io.Writer) int {
+	fs, load := newFlagSet("print", stderr)
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return usageError(stderr, "print [-expand] [-f FILE]...")
+	}
+
+	vars, err := loadFiles(load.files.list(), load.opts, stderr)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	for _, key := range sortedKeys(vars) {
+		_, _ = fmt.Fprintf(stdout, "export %s=%s\n", key, shellQuote(vars[key]))
+	}
+	return exitOK
+}
+
+func runCheck(args []string, stdout, stderr io.Writer) int {
+	fs, load := newFlagSet("check", stderr)
+	example := fs.String("example", ".env.example", "file listing the required variables")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return usageError(stderr, "check [-expand] [-example FILE] [-f FILE]...")
+	}
+
+	// Example files usually leave values empty and only their names are needed, so empty values
+	// are kept and `KEY: value` lines accepted
+	examples, issues, err := fileops.ParseEnvFile(*example, fileops.EnvOptions{KeepEmpty: true, AllowColon: true})
+	if err != nil {
+		return fail(stderr, err)
+	}
+	_ = fileops.WriteIssuesText(stderr, *example, issues)
+	vars, err := loadFiles(load.files.list(), load.opts, stderr)
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	code := exitOK
+	required := make(map[string]bool, len(examples))
+	for _, pair := range examples {
+		if required[pair.Key] {
+			continue
+		}
+		required[pair.Key] = true
+		if _, ok := vars[pair.Key]; ok {
+			continue
+		}
+		if _, ok := os.LookupEnv(pair.Key); !ok {
+			_, _ = fmt.Fprintf(stdout, "%s:%d: %s is not set\n", *example, pair.LineNbr, pair.Key)
+			code = exitNegative
+		}
+	}
+	for _, key := range sortedKeys(vars) {
+		if !required[key] {
+			_, _ = fmt.Fprintf(stderr, "rutil-env: %s is not in %s\n", key, *example)
+		}
+	}
+	return code
+}
+
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	fs, load := newFlagSet("diff", stderr)
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 || len(load.files) > 0 {
+		return usageError(stderr, "diff [-expand] FILE_A FILE_B")
+	}
+	args = fs.Args()
+
+	a, err := loadFiles(args[:1], load.opts, stderr)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	b, err := loadFiles(args[1:], load.opts, stderr)
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	keys := sortedKeys(a)
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, key := range keys {
+		valA, inA := a[key]
+		valB, inB := b[key]
+		if inA && inB && valA == valB {
+			continue
+		}
+		if inA {
+			lines = append(lines, "-"+key+"="+valA)
+		}
+		if inB {
+			lines = append(lines, "+"+key+"="+valB)
+		}
+	}
+	if len(lines) == 0 {
+		return exitOK
+	}
+
+	_, _ = fmt.Fprintf(stdout, "--- %s\n+++ %s\n%s\n", args[0], args[1], strings.Join(lines, "\n"))
+	return exitNegative
+}
+
+// loadFiles returns the variables set by files, later files overriding earlier ones.
+// Issues are reported on stderr
+func loadFiles(files []string, opts fileops.EnvOptions, stderr io.Writer) (vars map[string]string, err error) {
+	vars = make(map[string]string, 16)
+	for _, filespec := range files {
+		pairs, issues, err := fileops.ParseEnvFile(filespec, opts)
+		if err != nil {
+			return nil, err
+		}
+		_ = fileops.WriteIssuesText(stderr, filespec, issues)
+
+		for _, pair := range pairs {
+			vars[pair.Key] = pair.Value
+		}
+	}
+	return vars, nil
+}
+
+// shellQuote single quotes val for POSIX shells
+func shellQuote(val string) string {
+	return "'" + strings.ReplaceAll(val, "'", `'\''`) + "'"
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fileList is a repeatable -f flag
+type fileList []string
+
+func (f *fileList) String() string { return strings.Join(*f, ",") }
+
+func (f *fileList) Set(val string) error {
+	*f = append(*f, val)
+	return nil
+}
+
+// list returns the files given, or .env if none were
+func (f *fileList) list() []string {
+	if len(*f) == 0 {
+		return []string{".env"}
+	}
+	return *f
+}
+
+// loadFlags are the flags common to all commands
+type loadFlags struct {
+	files fileList
+	opts  fileops.EnvOptions
+}
+
+func newFlagSet(name string, stderr io.Writer) (*flag.FlagSet, *loadFlags) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	load := &loadFlags{}
+	fs.Var(&load.files, "f", "env file to load, may be repeated (default .env)")
+	fs.BoolVar(&load.opts.Expand, "expand", false, "expand $VAR and ${VAR} references in values")
+	return fs, load
+}
+
+func usageError(stderr io.Writer, synopsis string) int {
+	_, _ = fmt.Fprintln(stderr, "usage: rutil-env "+synopsis)
+	return exitError
+}
+
+func fail(stderr io.Writer, err error) int {
+	_, _ = fmt.Fprintln(stderr, "rutil-env:", err)
+	return exitError
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		filespec := filepath.Join(dir, name)
+		if err := os.WriteFile(filespec, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return filespec
+	}
+	base := write(".env", "DB_HOST=localhost\nGREETING='it''s' # comment\nDB_PORT=5432\n")
+	local := write(".env.local", "DB_HOST=db.local\n")
+	expand := write(".env.expand", "DB_HOST=localhost\nDB_URL=postgres://$DB_HOST/app\n")
+	example := write(".env.example", "DB_HOST=\nexport DB_PORT=\nDB_USER=\n")
+	multiLine := write(".env.example.tls", "TLS_KEY=\"-----BEGIN KEY-----\nMIIEvQ==\n-----END KEY-----\"\nAPI_TOKEN: changeme\nDB_HOST=\n")
+	t.Setenv("RUTIL_ENV_TEST_PARENT", "kept")
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout string
+	}{
+		{name: "print", args: []string{"print", "-f", base},
+			wantStdout: "export DB_HOST='localhost'\nexport DB_PORT='5432'\nexport GREETING='it'\n"},
+		{name: "print override", args: []string{"print", "-f", base, "-f", local},
+			wantStdout: "export DB_HOST='db.local'\nexport DB_PORT='5432'\nexport GREETING='it'\n"},
+		{name: "print missing file", args: []string{"print", "-f", filepath.Join(dir, "none")}, wantCode: 2},
+		{name: "check missing", args: []string{"check", "-example", example, "-f", base}, wantCode: 1,
+			wantStdout: example + ":3: DB_USER is not set\n"},
+		{name: "check multi-line and colon", args: []string{"check", "-example", multiLine, "-f", base}, wantCode: 1,
+			wantStdout: multiLine + ":1: TLS_KEY is not set\n" + multiLine + ":4: API_TOKEN is not set\n"},
+		{name: "diff", args: []string{"diff", base, local}, wantCode: 1,
+			wantStdout: "--- " + base + "\n+++ " + local + "\n-DB_HOST=localhost\n+DB_HOST=db.local\n-DB_PORT=5432\n-GREETING=it\n"},
+		{name: "diff same", args: []string{"diff", base, base}},
+		{name: "print expand", args: []string{"print", "-expand", "-f", expand},
+			wantStdout: "export DB_HOST='localhost'\nexport DB_URL='postgres://localhost/app'\n"},
+		{name: "run", args: []string{"run", "-f", base, "-f", local, "--", "sh", "-c", "echo $DB_HOST $RUTIL_ENV_TEST_PARENT"},
+			wantStdout: "db.local kept\n"},
+		{name: "run exit code", args: []string{"run", "-f", base, "sh", "-c", "exit 3"}, wantCode: 3},
+		{name: "run no command", args: []string{"run", "-f", base}, wantCode: 2},
+		{name: "unknown command", args: []string{"frob"}, wantCode: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, strings.NewReader(""), &stdout, &stderr); code != tt.wantCode {
+				t.Errorf("Expected exit code %d, got %d (stderr %q)", tt.wantCode, code, stderr.String())
+			}
+			if stdout.String() != tt.wantStdout {
+				t.Errorf("Expected stdout %q, got %q", tt.wantStdout, stdout.String())
+			}
+			if _, ok := os.LookupEnv("DB_HOST"); ok {
+				t.Error("Expected the environment of this process to be left alone")
+			}
+			if os.Getenv("RUTIL_ENV_TEST_PARENT") != "kept" {
+				t.Error("Expected the environment of this process to be restored")
+			}
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct{ val, expected string }{
+		{"plain", "'plain'"},
+		{"", "''"},
+		{"it's $HOME", `'it'\''s $HOME'`},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.val); got != tt.expected {
+			t.Errorf("Expected %s, got %s", tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+// Command rutil-ini reads and edits ini files from shell scripts.
+//
+//	rutil-ini get FILE SECTION::KEY
+//	rutil-ini set FILE SECTION::KEY VALUE
+//	rutil-ini delete FILE SECTION::KEY | SECTION
+//	rutil-ini list FILE [SECTION]
+//	rutil-ini validate [-format text|json|sarif] FILE SCHEMA
+//	rutil-ini fmt [-check] [-sort] [-align] [-quotes keep|always] FILE...
+//	rutil-ini diff [-json] FILE_A FILE_B
+//
+// Exit status is 0 on success, 1 when a key or section is not found, a file is invalid,
+// not formatted (with -check) or the files differ, and 2 on usage or read errors or when set
+// is given a key or value an ini file can't hold, such as a value spanning lines
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-rutil/rutil/fileops"
+	"github.com/go-serr/serr"
+)
+
+const (
+	exitOK       = 0
+	exitNegative = 1 // not found, invalid, not formatted or different
+	exitError    = 2
+)
+
+const usage = `usage: rutil-ini <command> [arguments]
+
+commands:
+  get FILE SECTION::KEY              print the value of a key
+  set FILE SECTION::KEY VALUE        set a key, preserving comments and layout
+  delete FILE SECTION::KEY|SECTION   delete a key or a whole section
+  list FILE [SECTION]                list the sections, or the keys of a section
+  validate FILE SCHEMA               validate against a .json or .schema.ini schema
+  fmt FILE...                        format files in place
+  diff FILE_A FILE_B                 show the differences between two files
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command in args, returning the exit status
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		_, _ = fmt.Fprint(stderr, usage)
+		return exitError
+	}
+
+	cmds := map[string]func([]string, io.Writer, io.Writer) int{
+		"get": runGet, "set": runSet, "delete": runDelete, "list": runList,
+		"validate": runValidate, "fmt": runFmt, "diff": runDiff,
+	}
+	cmd, ok := cmds[args[0]]
+	if !ok {
+		if args[0] != "help" && args[0] != "-h" && args[0] != "-help" {
+			_, _ = fmt.Fprintf(stderr, "rutil-ini: unknown command %q\n", args[0])
+		}
+		_, _ = fmt.Fprint(stderr, usage)
+		return exitError
+	}
+	return cmd(args[1:], stdout, stderr)
+}
+
+func runGet(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 2 {
+		return usageError(stderr, "get FILE SECTION::KEY")
+	}
+	doc, code := readDoc(args[0], stderr)
+	if doc == nil {
+		return code
+	}
+
+	section, key, ok := splitSectKey(args[1])
+	if !ok {
+		return usageError(stderr, "get FILE SECTION::KEY")
+	}
+	val, found := doc.Get(section, key)
+	if !found {
+		_, _ = fmt.Fprintf(stderr, "rutil-ini: %s not found in %s\n", args[1], args[0])
+		return exitNegative
+	}
+	_, _ = fmt.Fprintln(stdout, val)
+	return exitOK
+}
+
+func runSet(args []string, _, stderr io.Writer) int {
+	if len(args) != 3 {
+		return usageError(stderr, "set FILE SECTION::KEY VALUE")
+	}
+	section, key, ok := splitSectKey(args[1])
+	if !ok {
+		return usageError(stderr, "set FILE SECTION::KEY VALUE")
+	}
+
+	doc := &fileops.IniDoc{} // a missing file is created
+	if _, err := os.Stat(args[0]); !os.IsNotExist(err) {
+		if doc, _, err = fileops.ReadIniDoc(args[0]); err != nil {
+			return fail(stderr, err)
+		}
+	}
+	if err := doc.Set(section, key, args[2]); err != nil {
+		return fail(stderr, fmt.Errorf("%s: %w", args[1], err))
+	}
+	if err := doc.WriteFile(args[0]); err != nil {
+		return fail(stderr, err)
+	}
+	return exitOK
+}
+
+func runDelete(args []string, _, stderr io.Writer) int {
+	if len(args) != 2 {
+		return usageError(stderr, "delete FILE SECTION::KEY|SECTION")
+	}
+	doc, code := readDoc(args[0], stderr)
+	if doc == nil {
+		return code
+	}
+
+	var found bool
+	if section, key, ok := splitSectKey(args[1]); ok {
+		found = doc.Delete(section, key)
+	} else {
+		found = doc.DeleteSection(args[1])
+	}
+	if !found {
+		_, _ = fmt.Fprintf(stderr, "rutil-ini: %s not found in %s\n", args[1], args[0])
+		return exitNegative
+	}
+	if err := doc.WriteFile(args[0]); err != nil {
+		return fail(stderr, err)
+	}
+	return exitOK
+}
+
+func runList(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 && len(args) != 2 {
+		return usageError(stderr, "list FILE [SECTION]")
+	}
+	doc, code := readDoc(args[0], stderr)
+	if doc == nil {
+		return code
+	}
+
+	if len(args) == 1 {
+		for _, section := range doc.Sections() {
+			_, _ = fmt.Fprintln(stdout, section)
+		}
+		return exitOK
+	}
+
+	attrs, found := doc.AsMapOfSections()[args[1]]
+	if !found {
+		_, _ = fmt.Fprintf(stderr, "rutil-ini: section %s not found in %s\n", args[1], args[0])
+		return exitNegative
+	}
+	seen := make(map[string]bool, len(attrs))
+	for _, line := range doc.Lines {
+		if line.Kind == fileops.LineKeyValue && line.Section == args[1] && !seen[line.Key] {
+			if _, ok := attrs[line.Key]; ok {
+				seen[line.Key] = true
+				_, _ = fmt.Fprintln(stdout, line.Key)
+			}
+		}
+	}
+	return exitOK
+}
+
+func runValidate(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("validate FILE SCHEMA", stderr)
+	format := fs.String("format", "text", "output format: text, json or sarif")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 {
+		return usageError(stderr, "validate [-format text|json|sarif] FILE SCHEMA")
+	}
+	filespec, schemaFile := fs.Arg(0), fs.Arg(1)
+
+	var schema fileops.Schema
+	var issues []serr.SErr
+	var err error
+	if strings.HasSuffix(schemaFile, ".json") {
+		schema, err = fileops.ReadSchemaJSON(schemaFile)
+	} else {
+		schema, issues, err = fileops.ReadSchemaIni(schemaFile)
+	}
+	if err != nil {
+		return fail(stderr, err)
+	}
+	if len(issues) > 0 {
+		_ = fileops.WriteIssuesText(stderr, schemaFile, issues)
+		return exitError
+	}
+
+	doc, issues, err := fileops.ReadIniDoc(filespec)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	issues = append(issues, fileops.Validate(doc.AsMapOfSections(), schema, doc.Positions())...)
+
+	switch *format {
+	case "text":
+		err = fileops.WriteIssuesText(stdout, filespec, issues)
+	case "json", "sarif":
+		var data []byte
+		if *format == "json" {
+			data, err = fileops.IssuesJSON(filespec, issues)
+		} else {
+			data, err = fileops.IssuesSARIF("rutil-ini", filespec, issues)
+		}
+		if err == nil {
+			_, err = fmt.Fprintln(stdout, string(data))
+		}
+	default:
+		return usageError(stderr, "validate [-format text|json|sarif] FILE SCHEMA")
+	}
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	if len(issues) > 0 {
+		return exitNegative
+	}
+	return exitOK
+}
+
+func runFmt(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("fmt FILE...", stderr)
+	check := fs.Bool("check", false, "list files that are not formatted instead of changing them")
+	sortKeys := fs.Bool("sort", false, "sort keys within sections")
+	align := fs.Bool("align", false, "align inline comments")
+	quotes := fs.String("quotes", "", "quote values: keep or always (default as needed)")
+	if err := fs.Parse(args); err != nil || fs.NArg() == 0 {
+		return usageError(stderr, "fmt [-check] [-sort] [-align] [-quotes keep|always] FILE...")
+	}
+
+	opts := fileops.FormatOptions{SortKeys: *sortKeys, AlignComments: *align}
+	switch *quotes {
+	case "":
+	case "keep":
+		opts.Quotes = fileops.QuoteKeep
+	case "always":
+		opts.Quotes = fileops.QuoteAlways
+	default:
+		return usageError(stderr, "fmt [-check] [-sort] [-align] [-quotes keep|always] FILE...")
+	}
+
+	code := exitOK
+	for _, filespec := range fs.Args() {
+		changed, issues, err := fileops.FormatIniFile(filespec, opts, *check)
+		if err != nil {
+			code = fail(stderr, err)
+			continue
+		}
+		_ = fileops.WriteIssuesText(stderr, filespec, issues)
+		if changed && *check {
+			_, _ = fmt.Fprintln(stdout, filespec)
+			code = max(code, exitNegative)
+		}
+	}
+	return code
+}
+
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("diff FILE_A FILE_B", stderr)
+	asJSON := fs.Bool("json", false, "output the differences as JSON")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 {
+		return usageError(stderr, "diff [-json] FILE_A FILE_B")
+	}
+
+	diff, _, err := fileops.DiffIniFiles(fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	if *asJSON {
+		data, err := diff.JSON()
+		if err != nil {
+			return fail(stderr, err)
+		}
+		_, _ = fmt.Fprintln(stdout, string(data))
+	} else {
+		_, _ = fmt.Fprint(stdout, diff.Unified(fs.Arg(0), fs.Arg(1)))
+	}
+
+	if !diff.Empty() {
+		return exitNegative
+	}
+	return exitOK
+}
+
+// readDoc reads filespec, returning a nil doc and the exit status on error
+func readDoc(filespec string, stderr io.Writer) (*fileops.IniDoc, int) {
+	doc, _, err := fileops.ReadIniDoc(filespec)
+	if err != nil {
+		return nil, fail(stderr, err)
+	}
+	return doc, exitOK
+}
+
+// splitSectKey splits "section::key", as keys are flattened by the readers
+func splitSectKey(sectKey string) (section, key string, ok bool) {
+	section, key, ok = strings.Cut(sectKey, "::")
+	return section, key, ok && section != "" && key != ""
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+func usageError(stderr io.Writer, synopsis string) int {
+	_, _ = fmt.Fprintln(stderr, "usage: rutil-ini "+synopsis)
+	return exitError
+}
+
+func fail(stderr io.Writer, err error) int {
+	_, _ = fmt.Fprintln(stderr, "rutil-ini:", err)
+	return exitError
+}
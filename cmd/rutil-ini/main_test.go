@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	const appIni = `# Database settings
+[database]
+host = localhost # primary
+port = 5432
+
+[cache]
+ttl=5
+`
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "app.schema.ini")
+	if err := os.WriteFile(schema, []byte("[database]\nhost = string; required\nport = int; min=1; max=65535\n\n[cache]\nttl = int\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		args       []string // "FILE" is replaced by a fresh copy of appIni
+		wantCode   int
+		wantStdout string
+		wantFile   string // expected contents of FILE afterwards, if not empty
+	}{
+		{name: "get", args: []string{"get", "FILE", "database::host"}, wantStdout: "localhost\n"},
+		{name: "get missing", args: []string{"get", "FILE", "database::user"}, wantCode: 1},
+		{name: "get bad key", args: []string{"get", "FILE", "host"}, wantCode: 2},
+		{name: "get missing file", args: []string{"get", filepath.Join(dir, "none.ini"), "a::b"}, wantCode: 2},
+		{name: "set keeps comments", args: []string{"set", "FILE", "database::host", "db.example.com"},
+			wantFile: strings.Replace(appIni, "localhost", "db.example.com", 1)},
+		{name: "set new section", args: []string{"set", "FILE", "log::level", "debug"},
+			wantFile: appIni + "\n[log]\nlevel = debug\n"},
+		{name: "set unwritable value", args: []string{"set", "FILE", "database::host", `it's "x" #1`},
+			wantCode: 2, wantFile: appIni},
+		{name: "set multi-line value", args: []string{"set", "FILE", "log::level", "a\nb"},
+			wantCode: 2, wantFile: appIni},
+		{name: "set unwritable key", args: []string{"set", "FILE", "database::a=b", "v"},
+			wantCode: 2, wantFile: appIni},
+		{name: "delete key", args: []string{"delete", "FILE", "database::port"},
+			wantFile: strings.Replace(appIni, "port = 5432\n", "", 1)},
+		{name: "delete section", args: []string{"delete", "FILE", "database"},
+			wantFile: "[cache]\nttl=5\n"},
+		{name: "delete missing", args: []string{"delete", "FILE", "log"}, wantCode: 1},
+		{name: "list sections", args: []string{"list", "FILE"}, wantStdout: "database\ncache\n"},
+		{name: "list keys", args: []string{"list", "FILE", "database"}, wantStdout: "host\nport\n"},
+		{name: "list missing", args: []string{"list", "FILE", "log"}, wantCode: 1},
+		{name: "validate", args: []string{"validate", "FILE", schema}},
+		{name: "fmt check", args: []string{"fmt", "-check", "FILE"}, wantCode: 1, wantStdout: "FILE\n"},
+		{name: "fmt", args: []string{"fmt", "FILE"}, wantFile: strings.Replace(appIni, "ttl=5", "ttl = 5", 1)},
+		{name: "diff same", args: []string{"diff", "FILE", "FILE"}},
+		{name: "unknown command", args: []string{"frob"}, wantCode: 2},
+		{name: "no command", wantCode: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "app.ini")
+			if err := os.WriteFile(file, []byte(appIni), 0644); err != nil {
+				t.Fatal(err)
+			}
+			args := make([]string, len(tt.args))
+			for i, arg := range tt.args {
+				args[i] = strings.ReplaceAll(arg, "FILE", file)
+			}
+
+			var stdout, stderr bytes.Buffer
+			if code := run(args, &stdout, &stderr); code != tt.wantCode {
+				t.Errorf("Expected exit code %d, got %d (stderr %q)", tt.wantCode, code, stderr.String())
+			}
+			if want := strings.ReplaceAll(tt.wantStdout, "FILE", file); stdout.String() != want {
+				t.Errorf("Expected stdout %q, got %q", want, stdout.String())
+			}
+			if tt.wantFile != "" {
+				got, _ := os.ReadFile(file)
+				if string(got) != tt.wantFile {
+					t.Errorf("Expected file:\n%s\ngot:\n%s", tt.wantFile, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRunValidateAndDiff(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		filespec := filepath.Join(dir, name)
+		if err := os.WriteFile(filespec, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return filespec
+	}
+	a := write("a.ini", "[database]\nhost = localhost\nport = 99999\n")
+	b := write("b.ini", "[database]\nhost = db\nport = 99999\n")
+	schema := write("app.schema.json", `{"sections": {"database": {"keys": {"host": {"type": "string"}, "port": {"type": "int", "max": 65535}}}}}`)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"validate", a, schema}, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected exit code 1, got %d (stderr %q)", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), a+":3:") {
+		t.Errorf("Expected a positioned issue, got %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"validate", "-format", "sarif", a, schema}, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stdout.String(), `"version": "2.1.0"`) {
+		t.Errorf("Expected SARIF output, got %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"diff", a, b}, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	expected := "--- " + a + "\n+++ " + b + "\n@@ [database] @@\n-host = localhost\n+host = db\n"
+	if stdout.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, stdout.String())
+	}
+}
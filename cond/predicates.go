@@ -0,0 +1,45 @@
+package cond
+
+// Pred is a predicate over T, the building block for composing conditions
+type Pred[T any] func(T) bool
+
+// And returns a predicate that is true when all preds are true.
+// Evaluation short-circuits on the first false predicate. With no preds it is true
+func And[T any](preds ...Pred[T]) Pred[T] {
+	return func(t T) bool {
+		for _, p := range preds {
+			if !p(t) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or returns a predicate that is true when any of preds is true.
+// Evaluation short-circuits on the first true predicate. With no preds it is false
+func Or[T any](preds ...Pred[T]) Pred[T] {
+	return func(t T) bool {
+		for _, p := range preds {
+			if p(t) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not returns a predicate that negates p
+func Not[T any](p Pred[T]) Pred[T] {
+	return func(t T) bool {
+		return !p(t)
+	}
+}
+
+// Implies returns a predicate for "if p then q", which is true whenever p is false.
+// q is only evaluated when p is true
+func Implies[T any](p, q Pred[T]) Pred[T] {
+	return func(t T) bool {
+		return !p(t) || q(t)
+	}
+}
@@ -0,0 +1,47 @@
+package cond
+
+import (
+	"testing"
+)
+
+func TestPredicates(t *testing.T) {
+	isEven := Pred[int](func(n int) bool { return n%2 == 0 })
+	isPositive := Pred[int](func(n int) bool { return n > 0 })
+
+	tests := []struct {
+		name     string
+		pred     Pred[int]
+		input    int
+		expected bool
+	}{
+		{"And true", And(isEven, isPositive), 4, true},
+		{"And false", And(isEven, isPositive), -4, false},
+		{"And empty", And[int](), 1, true},
+		{"Or true", Or(isEven, isPositive), 3, true},
+		{"Or false", Or(isEven, isPositive), -3, false},
+		{"Or empty", Or[int](), 1, false},
+		{"Not", Not(isEven), 3, true},
+		{"Implies antecedent false", Implies(isEven, isPositive), 3, true},
+		{"Implies both true", Implies(isEven, isPositive), 2, true},
+		{"Implies consequent false", Implies(isEven, isPositive), -2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.pred(tt.input); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("Short circuit", func(t *testing.T) {
+		called := false
+		spy := Pred[int](func(int) bool { called = true; return true })
+		Implies(isEven, spy)(3)
+		And(isEven, spy)(3)
+		Or(isPositive, spy)(3)
+		if called {
+			t.Error("Expected later predicates not to be evaluated")
+		}
+	})
+}
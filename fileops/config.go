@@ -0,0 +1,112 @@
+package fileops
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Snapshot is an immutable view of config sections, safe to share between goroutines
+type Snapshot struct {
+	sections map[string]map[string]string
+}
+
+// NewSnapshot returns a Snapshot holding a copy of sections
+func NewSnapshot(sections map[string]map[string]string) *Snapshot {
+	return &Snapshot{sections: copySections(sections)}
+}
+
+// Get returns the value of key in section
+func (s *Snapshot) Get(section, key string) (val string, ok bool) {
+	val, ok = s.sections[section][key]
+	return
+}
+
+// Section returns a copy of the key values of section, or nil if it doesn't exist
+func (s *Snapshot) Section(section string) map[string]string {
+	attrs, ok := s.sections[section]
+	if !ok {
+		return nil
+	}
+	out := make(map[string]string, len(attrs))
+	for key, val := range attrs {
+		out[key] = val
+	}
+	return out
+}
+
+// Sections returns the section names, sorted
+func (s *Snapshot) Sections() []string {
+	return sortedKeys(s.sections)
+}
+
+// AsMapOfSections returns a copy of all sections
+func (s *Snapshot) AsMapOfSections() map[string]map[string]string {
+	return copySections(s.sections)
+}
+
+// Config holds the current Snapshot of a config, allowing lock-free reads while it is replaced
+type Config struct {
+	snap atomic.Pointer[Snapshot]
+
+	swapMu sync.Mutex // serializes swaps so subscribers see them in order
+	subsMu sync.Mutex
+	subs   []func(old, new *Snapshot)
+}
+
+// NewConfig returns a Config holding a snapshot of sections
+func NewConfig(sections map[string]map[string]string) *Config {
+	c := &Config{}
+	c.snap.Store(NewSnapshot(sections))
+	return c
+}
+
+// Load returns the current snapshot
+func (c *Config) Load() *Snapshot {
+	return c.snap.Load()
+}
+
+// Swap replaces the current snapshot with a snapshot of sections, returning the old one.
+// Subscribers are called before Swap returns and must not call Swap themselves
+func (c *Config) Swap(sections map[string]map[string]string) (old *Snapshot) {
+	next := NewSnapshot(sections)
+
+	c.swapMu.Lock()
+	defer c.swapMu.Unlock()
+
+	old = c.snap.Swap(next)
+
+	c.subsMu.Lock()
+	subs := append([]func(old, new *Snapshot){}, c.subs...)
+	c.subsMu.Unlock()
+
+	for _, fn := range subs {
+		fn(old, next)
+	}
+	return
+}
+
+// Subscribe registers fn to be called with the old and new snapshots after each Swap
+func (c *Config) Subscribe(fn func(old, new *Snapshot)) {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	c.subs = append(c.subs, fn)
+}
+
+// Watch swaps in each config reloaded by w
+func (c *Config) Watch(w *Watcher) {
+	w.Subscribe(func(sections map[string]map[string]string, _ []Change) {
+		c.Swap(sections)
+	})
+}
+
+// copySections returns a deep copy of sections
+func copySections(sections map[string]map[string]string) map[string]map[string]string {
+	out := make(map[string]map[string]string, len(sections))
+	for section, attrs := range sections {
+		out[section] = make(map[string]string, len(attrs))
+		for key, val := range attrs {
+			out[section][key] = val
+		}
+	}
+	return out
+}
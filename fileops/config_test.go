@@ -0,0 +1,110 @@
+package fileops
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConfig(t *testing.T) {
+	sections := map[string]map[string]string{"db": {"host": "a"}}
+	cfg := NewConfig(sections)
+
+	t.Run("snapshot is a copy", func(t *testing.T) {
+		sections["db"]["host"] = "changed"
+		if val, _ := cfg.Load().Get("db", "host"); val != "a" {
+			t.Errorf("Expected a, got %q", val)
+		}
+
+		attrs := cfg.Load().Section("db")
+		attrs["host"] = "changed"
+		if val, _ := cfg.Load().Get("db", "host"); val != "a" {
+			t.Errorf("Expected a, got %q", val)
+		}
+		if cfg.Load().Section("missing") != nil {
+			t.Error("Expected nil for missing section")
+		}
+	})
+
+	t.Run("swap and subscribe", func(t *testing.T) {
+		var olds, news []string
+		cfg.Subscribe(func(old, new *Snapshot) {
+			o, _ := old.Get("db", "host")
+			n, _ := new.Get("db", "host")
+			olds, news = append(olds, o), append(news, n)
+		})
+
+		old := cfg.Swap(map[string]map[string]string{"db": {"host": "b"}, "cache": {}})
+		if val, _ := old.Get("db", "host"); val != "a" {
+			t.Errorf("Expected old snapshot a, got %q", val)
+		}
+		if !reflect.DeepEqual(cfg.Load().Sections(), []string{"cache", "db"}) {
+			t.Errorf("Unexpected sections %v", cfg.Load().Sections())
+		}
+		if !reflect.DeepEqual(olds, []string{"a"}) || !reflect.DeepEqual(news, []string{"b"}) {
+			t.Errorf("Unexpected notifications %v -> %v", olds, news)
+		}
+	})
+
+	// Run with -race to prove safe concurrent use
+	t.Run("concurrent reads and swaps", func(t *testing.T) {
+		c := NewConfig(map[string]map[string]string{"db": {"gen": "0"}})
+		c.Subscribe(func(old, new *Snapshot) { _, _ = new.Get("db", "gen") })
+
+		var wg sync.WaitGroup
+		for r := 0; r < 8; r++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					snap := c.Load()
+					if _, ok := snap.Get("db", "gen"); !ok {
+						t.Error("Expected gen in every snapshot")
+						return
+					}
+					_ = snap.AsMapOfSections()
+				}
+			}()
+		}
+		for s := 0; s < 2; s++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 200; i++ {
+					c.Swap(map[string]map[string]string{"db": {"gen": fmt.Sprint(i)}})
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func TestConfigWatch(t *testing.T) {
+	filespec := filepath.Join(t.TempDir(), "app.ini")
+	if err := os.WriteFile(filespec, []byte("[db]\nhost = a\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	w, _, err := NewWatcher(filespec)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	w.Debounce = time.Millisecond
+
+	cfg := NewConfig(w.Current())
+	cfg.Watch(w)
+
+	if err := os.WriteFile(filespec, []byte("[db]\nhost = bb\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	w.Poll(context.Background())
+
+	if val, _ := cfg.Load().Get("db", "host"); val != "bb" {
+		t.Errorf("Expected bb, got %q", val)
+	}
+}
@@ -0,0 +1,137 @@
+package fileops
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-serr/serr"
+)
+
+// EnvBuilder builds the environment of a child process, such as exec.Cmd.Env, from .env files
+// without changing the environment of this process, so builders can be used concurrently.
+// An EnvBuilder itself is not safe for concurrent use; Clone one per child instead
+type EnvBuilder struct {
+	vars  map[string]string
+	allow []string
+	deny  []string
+}
+
+// NewEnvBuilder returns an EnvBuilder starting from the environment of this process if
+// inherit is true, otherwise empty
+func NewEnvBuilder(inherit bool) *EnvBuilder {
+	b := &EnvBuilder{vars: make(map[string]string, 64)}
+	if inherit {
+		for _, kv := range os.Environ() {
+			if key, val, ok := strings.Cut(kv, "="); ok && key != "" {
+				b.vars[key] = val
+			}
+		}
+	}
+	return b
+}
+
+// Clone returns a copy of the builder which can be changed independently
+func (b *EnvBuilder) Clone() *EnvBuilder {
+	clone := &EnvBuilder{vars: make(map[string]string, len(b.vars)),
+		allow: append([]string{}, b.allow...), deny: append([]string{}, b.deny...)}
+	for key, val := range b.vars {
+		clone.vars[key] = val
+	}
+	return clone
+}
+
+// Set sets key to val
+func (b *EnvBuilder) Set(key, val string) *EnvBuilder {
+	b.vars[key] = val
+	return b
+}
+
+// Unset removes keys
+func (b *EnvBuilder) Unset(keys ...string) *EnvBuilder {
+	for _, key := range keys {
+		delete(b.vars, key)
+	}
+	return b
+}
+
+// Lookup returns the value of key, as os.LookupEnv does for this process
+func (b *EnvBuilder) Lookup(key string) (val string, ok bool) {
+	val, ok = b.vars[key]
+	return
+}
+
+// Allow limits Environ to variables whose names match one of patterns, as path.Match
+// globs such as "AWS_*". Repeated calls add patterns
+func (b *EnvBuilder) Allow(patterns ...string) *EnvBuilder {
+	b.allow = append(b.allow, patterns...)
+	return b
+}
+
+// Deny leaves variables whose names match one of patterns out of Environ, even if allowed
+func (b *EnvBuilder) Deny(patterns ...string) *EnvBuilder {
+	b.deny = append(b.deny, patterns...)
+	return b
+}
+
+// ApplyPairs sets pairs in order. Unless override is set, keys already in the builder
+// before the call are left alone
+func (b *EnvBuilder) ApplyPairs(pairs []EnvPair, override bool) *EnvBuilder {
+	preset := make(map[string]bool, len(pairs))
+	if !override {
+		for _, pair := range pairs {
+			_, preset[pair.Key] = b.vars[pair.Key]
+		}
+	}
+	for _, pair := range pairs {
+		if !preset[pair.Key] {
+			b.vars[pair.Key] = pair.Value
+		}
+	}
+	return b
+}
+
+// ApplyFile reads a `*.env` style file as ParseEnvFile does and sets its variables, overriding
+// those already in the builder. Variables are expanded against the builder rather than this
+// process if the options given ask for it
+func (b *EnvBuilder) ApplyFile(filespec string, optOpts ...EnvOptions) (issues []serr.SErr, err error) {
+	opts := EnvOptions{}
+	if len(optOpts) > 0 {
+		opts = optOpts[0]
+	}
+	expand := opts.Expand
+	opts.Expand = false
+
+	pairs, issues, err := ParseEnvFile(filespec, opts)
+	if err != nil {
+		return issues, err
+	}
+	if expand {
+		issues = append(issues, expandEnvPairs(pairs, b.Lookup)...)
+	}
+
+	b.ApplyPairs(pairs, true)
+	return issues, nil
+}
+
+// Environ returns the variables as sorted "KEY=value" strings for exec.Cmd.Env,
+// filtered by Allow and Deny. Invalid patterns match nothing
+func (b *EnvBuilder) Environ() (env []string) {
+	env = make([]string, 0, len(b.vars))
+	for _, key := range sortedKeys(b.vars) {
+		if len(b.allow) > 0 && !matchesAny(key, b.allow) || matchesAny(key, b.deny) {
+			continue
+		}
+		env = append(env, key+"="+b.vars[key])
+	}
+	return
+}
+
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package fileops
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEnvBuilder(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		filespec := filepath.Join(dir, name)
+		if err := os.WriteFile(filespec, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return filespec
+	}
+	base := write("base.env", "APP_HOST=localhost\nAPP_URL=http://$APP_HOST:${APP_PORT:-80}\nAWS_SECRET=s3cr3t\n")
+	job := write("job.env", "APP_HOST=job.local\nJOB_ID=42\n")
+	t.Setenv("BUILDER_PARENT", "parent")
+	t.Setenv("APP_PORT", "9999") // not in the builder, so not used for expansion
+
+	t.Run("files and expansion", func(t *testing.T) {
+		b := NewEnvBuilder(false).Set("APP_PORT", "8080")
+		if _, err := b.ApplyFile(base, EnvOptions{Expand: true}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := b.ApplyFile(job); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := []string{"APP_HOST=job.local", "APP_PORT=8080", "APP_URL=http://localhost:8080",
+			"AWS_SECRET=s3cr3t", "JOB_ID=42"}
+		if got := b.Environ(); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected %v, got %v", expected, got)
+		}
+		if _, ok := os.LookupEnv("JOB_ID"); ok {
+			t.Error("Expected the environment of this process to be left alone")
+		}
+		if _, err := b.ApplyFile(filepath.Join(dir, "missing.env")); err == nil {
+			t.Error("Expected an error for a missing file")
+		}
+	})
+
+	t.Run("unset, allow and deny", func(t *testing.T) {
+		b := NewEnvBuilder(false).
+			ApplyPairs([]EnvPair{{Key: "APP_A", Value: "1"}, {Key: "APP_B", Value: "2"}, {Key: "AWS_KEY", Value: "k"},
+				{Key: "HOME", Value: "/root"}}, true).
+			Unset("APP_B").
+			Allow("APP_*", "AWS_*").
+			Deny("AWS_*")
+		if got, expected := b.Environ(), []string{"APP_A=1"}; !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected %v, got %v", expected, got)
+		}
+	})
+
+	t.Run("no override", func(t *testing.T) {
+		b := NewEnvBuilder(false).Set("A", "kept").
+			ApplyPairs([]EnvPair{{Key: "A", Value: "x"}, {Key: "B", Value: "1"}, {Key: "B", Value: "2"}}, false)
+		if got, expected := b.Environ(), []string{"A=kept", "B=2"}; !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected %v, got %v", expected, got)
+		}
+	})
+
+	t.Run("inherit and clone", func(t *testing.T) {
+		parent := NewEnvBuilder(true)
+		if val, _ := parent.Lookup("BUILDER_PARENT"); val != "parent" {
+			t.Errorf("Expected the environment to be inherited, got %q", val)
+		}
+		child := parent.Clone().Set("BUILDER_PARENT", "child").Deny("PATH")
+		if val, _ := parent.Lookup("BUILDER_PARENT"); val != "parent" {
+			t.Errorf("Expected the clone to be independent, got %q", val)
+		}
+		for _, kv := range child.Environ() {
+			if strings.HasPrefix(kv, "PATH=") {
+				t.Error("Expected PATH to be denied")
+			}
+		}
+	})
+
+	t.Run("concurrent children", func(t *testing.T) {
+		sh, err := exec.LookPath("sh")
+		if err != nil {
+			t.Skip("no sh")
+		}
+		parent := NewEnvBuilder(false)
+		if _, err := parent.ApplyFile(base); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var wg sync.WaitGroup
+		for _, host := range []string{"a", "b", "c", "d"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cmd := exec.Command(sh, "-c", `printf %s "$APP_HOST"`)
+				cmd.Env = parent.Clone().Set("APP_HOST", host).Environ()
+				out, err := cmd.Output()
+				if err != nil || string(out) != host {
+					t.Errorf("Expected %s, got %q (%v)", host, out, err)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
@@ -0,0 +1,70 @@
+package fileops
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/go-serr/serr"
+)
+
+// EnvCascadeFiles returns the .env files LoadEnvCascade considers in dir, in decreasing order of
+// precedence: .env.<appEnv>.local, .env.local, .env.<appEnv> and .env. Without an appEnv only
+// .env.local and .env are used, and for the "test" appEnv .env.local is left out so that tests
+// don't depend on a developer's local settings
+func EnvCascadeFiles(dir, appEnv string) (files []string) {
+	if appEnv != "" {
+		files = append(files, ".env."+appEnv+".local")
+	}
+	if appEnv != "test" {
+		files = append(files, ".env.local")
+	}
+	if appEnv != "" {
+		files = append(files, ".env."+appEnv)
+	}
+	files = append(files, ".env")
+
+	for i, file := range files {
+		files[i] = filepath.Join(dir, file)
+	}
+	return
+}
+
+// LoadEnvCascade loads the files of EnvCascadeFiles into the environment, skipping missing files.
+// A variable takes its value from the file with the highest precedence, and variables already
+// set in the environment are never overridden. The provenance of every variable defined by the
+// files is returned: the file and line it was set from, or LayerEnv if it was already set
+func LoadEnvCascade(dir, appEnv string, optOpts ...EnvOptions) (provenance map[string]Source, issues []serr.SErr, err error) {
+	provenance = make(map[string]Source, 16)
+	for _, filespec := range EnvCascadeFiles(dir, appEnv) {
+		if _, statErr := os.Stat(filespec); errors.Is(statErr, fs.ErrNotExist) {
+			continue
+		}
+
+		pairs, fileIssues, err := ParseEnvFile(filespec, optOpts...)
+		for i := range fileIssues {
+			fileIssues[i].AppendKeyValPairs("file", filespec)
+		}
+		issues = append(issues, fileIssues...)
+		if err != nil {
+			return provenance, issues, err
+		}
+
+		// Higher precedence files were applied first, so neither they nor the environment are overridden
+		skipped, applyIssues := ApplyEnvPairs(pairs, false)
+		issues = append(issues, applyIssues...)
+
+		for _, key := range skipped {
+			if _, ok := provenance[key]; !ok {
+				provenance[key] = Source{Layer: LayerEnv, Name: key}
+			}
+		}
+		for _, pair := range pairs {
+			if src, ok := provenance[pair.Key]; !ok || src.Layer == LayerFile && src.Name == filespec {
+				provenance[pair.Key] = Source{Layer: LayerFile, Name: filespec, LineNbr: pair.LineNbr}
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,77 @@
+package fileops
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEnvCascadeFiles(t *testing.T) {
+	tests := []struct {
+		appEnv   string
+		expected []string
+	}{
+		{"", []string{".env.local", ".env"}},
+		{"production", []string{".env.production.local", ".env.local", ".env.production", ".env"}},
+		{"test", []string{".env.test.local", ".env.test", ".env"}},
+	}
+	for _, tt := range tests {
+		var expected []string
+		for _, file := range tt.expected {
+			expected = append(expected, filepath.Join("cfg", file))
+		}
+		if got := EnvCascadeFiles("cfg", tt.appEnv); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected %v for %q, got %v", expected, tt.appEnv, got)
+		}
+	}
+}
+
+func TestLoadEnvCascade(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".env":                  "CASCADE_A=env\nCASCADE_B=env\nCASCADE_C=env\nCASCADE_D=env\nCASCADE_PRESET=env\n",
+		".env.production":       "CASCADE_B=production\nCASCADE_C=production\n",
+		".env.local":            "CASCADE_C=local\nCASCADE_D=local\n",
+		".env.production.local": "CASCADE_D=production.local\nCASCADE_D=again\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, key := range []string{"CASCADE_A", "CASCADE_B", "CASCADE_C", "CASCADE_D"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	t.Setenv("CASCADE_PRESET", "orchestrator")
+
+	provenance, issues, err := LoadEnvCascade(dir, "production")
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("Unexpected error %v or issues %v", err, issues)
+	}
+
+	expectedEnv := map[string]string{"CASCADE_A": "env", "CASCADE_B": "production", "CASCADE_C": "local",
+		"CASCADE_D": "again", "CASCADE_PRESET": "orchestrator"}
+	for key, expected := range expectedEnv {
+		if got := os.Getenv(key); got != expected {
+			t.Errorf("Expected %s=%s, got %s", key, expected, got)
+		}
+	}
+
+	expectedProvenance := map[string]Source{
+		"CASCADE_A":      {Layer: LayerFile, Name: filepath.Join(dir, ".env"), LineNbr: 1},
+		"CASCADE_B":      {Layer: LayerFile, Name: filepath.Join(dir, ".env.production"), LineNbr: 1},
+		"CASCADE_C":      {Layer: LayerFile, Name: filepath.Join(dir, ".env.local"), LineNbr: 1},
+		"CASCADE_D":      {Layer: LayerFile, Name: filepath.Join(dir, ".env.production.local"), LineNbr: 2},
+		"CASCADE_PRESET": {Layer: LayerEnv, Name: "CASCADE_PRESET"},
+	}
+	if !reflect.DeepEqual(provenance, expectedProvenance) {
+		t.Errorf("Expected %v\ngot %v", expectedProvenance, provenance)
+	}
+
+	if _, _, err := LoadEnvCascade(filepath.Join(dir, "missing"), "production"); err != nil {
+		t.Errorf("Expected missing files to be skipped, got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package fileops
+
+import (
+	"encoding"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/go-serr/serr"
+)
+
+// EnvDecodeOptions controls DecodeEnv
+type EnvDecodeOptions struct {
+	Prefix string                      // prepended to every variable name, e.g. "MYAPP_"
+	Lookup func(string) (string, bool) // where variables are read from. Defaults to os.LookupEnv
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// DecodeEnv sets the fields of the struct pointed to by ptr from environment variables.
+// A field tagged `env:"NAME"` is set from variable NAME, or from its `default:"..."` tag if NAME
+// is unset or empty, and reported missing if it has neither and is tagged `required:"true"`.
+// A struct field tagged `env:"DB"` decodes its own fields with names prefixed "DB_", and untagged
+// struct fields are decoded with the current prefix. Supported field types are those of
+// BindStructFlags, encoding.TextUnmarshaler, pointers to these, and slices of these split on
+// the `sep:"..."` tag, by default ",". All missing and invalid variables are returned as
+// issues with the type expected, never the values, as they may be secrets. Unsupported field types are an error
+func DecodeEnv(ptr any, optOpts ...EnvDecodeOptions) (issues []serr.SErr, err error) {
+	opts := EnvDecodeOptions{}
+	if len(optOpts) > 0 {
+		opts = optOpts[0]
+	}
+	if opts.Lookup == nil {
+		opts.Lookup = os.LookupEnv
+	}
+
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, serr.New("DecodeEnv requires a pointer to a struct", "type", fmt.Sprintf("%T", ptr))
+	}
+	return decodeEnvStruct(rv.Elem(), opts.Prefix, "", opts.Lookup)
+}
+
+// decodeEnvStruct decodes the fields of sv. path is the dotted field path of sv, for issues
+func decodeEnvStruct(sv reflect.Value, prefix, path string, lookup func(string) (string, bool)) (
+	issues []serr.SErr, err error) {
+	st := sv.Type()
+
+	for i := 0; i < st.NumField(); i++ {
+		field := st.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := sv.Field(i)
+		fieldPath := path + field.Name
+		tag, tagged := field.Tag.Lookup("env")
+
+		if fv.Kind() == reflect.Struct && !reflect.PointerTo(fv.Type()).Implements(textUnmarshalerType) {
+			nestedPrefix := prefix
+			if tagged && tag != "" {
+				nestedPrefix = prefix + tag + "_"
+			}
+			structIssues, err := decodeEnvStruct(fv, nestedPrefix, fieldPath+".", lookup)
+			issues = append(issues, structIssues...)
+			if err != nil {
+				return issues, err
+			}
+			continue
+		}
+		if !tagged || tag == "" {
+			continue
+		}
+		if !isDecodable(fv.Type()) {
+			return issues, serr.New("Unsupported field type", "field", fieldPath, "type", fv.Type().String())
+		}
+
+		name := prefix + tag
+		val, _ := lookup(name)
+		if val == "" {
+			val = field.Tag.Get("default")
+		}
+		if val == "" {
+			if field.Tag.Get("required") == "true" {
+				issues = append(issues, serr.NewSErr("Required variable is not set", "var", name, "field", fieldPath))
+			}
+			continue
+		}
+
+		sep := field.Tag.Get("sep")
+		if sep == "" {
+			sep = ","
+		}
+		if err := decodeEnvValue(fv, val, sep); err != nil {
+			// The parse error quotes the value, so only the expected type is reported
+			issues = append(issues, serr.NewSErr("Invalid value for variable", "var", name, "field", fieldPath,
+				"expected", fv.Type().String()))
+		}
+	}
+	return
+}
+
+// decodeEnvValue parses val into fv, splitting slices on sep
+func decodeEnvValue(fv reflect.Value, val, sep string) error {
+	if reflect.PointerTo(fv.Type()).Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	}
+
+	switch fv.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(fv.Type().Elem())
+		if err := decodeEnvValue(elem.Elem(), val, sep); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	case reflect.Slice:
+		parts := strings.Split(val, sep)
+		slice := reflect.MakeSlice(fv.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := decodeEnvValue(slice.Index(i), strings.TrimSpace(part), sep); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+	return setFieldFromString(fv, val)
+}
+
+// isDecodable reports whether decodeEnvValue supports values of type t
+func isDecodable(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice:
+		return isDecodable(t.Elem())
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
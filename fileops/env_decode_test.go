@@ -0,0 +1,131 @@
+package fileops
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-serr/serr"
+)
+
+type decodeDBConfig struct {
+	Host string `env:"HOST" default:"localhost"`
+	Port int    `env:"PORT" default:"5432"`
+	User string `env:"USER" required:"true"`
+}
+
+type decodeConfig struct {
+	Name    string         `env:"NAME" required:"true"`
+	Debug   bool           `env:"DEBUG"`
+	Timeout time.Duration  `env:"TIMEOUT" default:"5s"`
+	Ratio   *float64       `env:"RATIO"`
+	Tags    []string       `env:"TAGS"`
+	Ports   []int          `env:"PORTS" sep:";"`
+	IP      net.IP         `env:"IP"`
+	When    time.Time      `env:"WHEN"`
+	DB      decodeDBConfig `env:"DB"`
+	Extra   struct {
+		Level string `env:"LEVEL"`
+	}
+	unexported string `env:"UNEXPORTED"`
+	Untagged   string
+}
+
+func TestDecodeEnv(t *testing.T) {
+	lookup := func(vars map[string]string) func(string) (string, bool) {
+		return func(name string) (string, bool) {
+			val, ok := vars[name]
+			return val, ok
+		}
+	}
+	ratio := 0.5
+
+	t.Run("all fields", func(t *testing.T) {
+		var cfg decodeConfig
+		issues, err := DecodeEnv(&cfg, EnvDecodeOptions{Prefix: "APP_", Lookup: lookup(map[string]string{
+			"APP_NAME": "svc", "APP_DEBUG": "true", "APP_RATIO": "0.5", "APP_TAGS": "a, b,c",
+			"APP_PORTS": "80;443", "APP_IP": "10.0.0.1", "APP_WHEN": "2026-01-02T03:04:05Z",
+			"APP_DB_USER": "admin", "APP_DB_PORT": "6543", "APP_LEVEL": "debug", "APP_UNEXPORTED": "x",
+		})})
+		if err != nil || len(issues) != 0 {
+			t.Fatalf("Unexpected error %v or issues %v", err, issues)
+		}
+
+		expected := decodeConfig{Name: "svc", Debug: true, Timeout: 5 * time.Second, Ratio: &ratio,
+			Tags: []string{"a", "b", "c"}, Ports: []int{80, 443}, IP: net.ParseIP("10.0.0.1"),
+			When: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			DB:   decodeDBConfig{Host: "localhost", Port: 6543, User: "admin"}}
+		expected.Extra.Level = "debug"
+		if !reflect.DeepEqual(cfg, expected) {
+			t.Errorf("Expected %+v\ngot %+v", expected, cfg)
+		}
+	})
+
+	t.Run("all issues at once", func(t *testing.T) {
+		var cfg decodeConfig
+		issues, err := DecodeEnv(&cfg, EnvDecodeOptions{Lookup: lookup(map[string]string{
+			"DEBUG": "maybe", "PORTS": "80;s3cr3t-port", "IP": "s3cr3t-ip", "DB_PORT": "", "TIMEOUT": "s3cr3t-hunter2",
+		})})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var got []string
+		for _, issue := range issues {
+			fields := issue.FieldsMap()
+			got = append(got, issue.Error()+" "+fields["var"]+" "+fields["field"])
+			for key, val := range fields {
+				if strings.Contains(val, "s3cr3t") || strings.Contains(val, "maybe") {
+					t.Errorf("Expected values to be left out of issues, got %s=%q", key, val)
+				}
+			}
+		}
+		if expected := fieldsOf(issues, "expected"); !reflect.DeepEqual(expected, []string{"", "bool", "time.Duration", "[]int", "net.IP", ""}) {
+			t.Errorf("Unexpected expected types %q", expected)
+		}
+		expected := []string{
+			"Required variable is not set NAME Name",
+			"Invalid value for variable DEBUG Debug",
+			"Invalid value for variable TIMEOUT Timeout",
+			"Invalid value for variable PORTS Ports",
+			"Invalid value for variable IP IP",
+			"Required variable is not set DB_USER DB.User",
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected %q\ngot %q", expected, got)
+		}
+	})
+
+	t.Run("process environment", func(t *testing.T) {
+		t.Setenv("DECODE_NAME", "from env")
+		var cfg struct {
+			Name string `env:"DECODE_NAME"`
+		}
+		if _, err := DecodeEnv(&cfg); err != nil || cfg.Name != "from env" {
+			t.Errorf("Expected from env, got %q (%v)", cfg.Name, err)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		var cfg decodeConfig
+		if _, err := DecodeEnv(cfg); err == nil {
+			t.Error("Expected an error for a non-pointer")
+		}
+		var unsupported struct {
+			M map[string]string `env:"M"`
+		}
+		if _, err := DecodeEnv(&unsupported); err == nil {
+			t.Error("Expected an error for an unsupported field type")
+		}
+	})
+}
+
+// fieldsOf returns the field key of each issue, or "" if it has none
+func fieldsOf(issues []serr.SErr, key string) (vals []string) {
+	for _, issue := range issues {
+		vals = append(vals, issue.FieldsMap()[key])
+	}
+	return
+}
@@ -0,0 +1,187 @@
+package fileops
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-serr/serr"
+)
+
+// envExpander expands the values of pairs in place
+type envExpander struct {
+	pairs     []EnvPair
+	lookupEnv func(string) (string, bool) // the environment variables resolve to after earlier keys
+	state     []int                       // expandTodo, expandInProgress or expandDone for each pair
+	issues    []serr.SErr
+}
+
+const (
+	expandTodo = iota
+	expandInProgress
+	expandDone
+)
+
+// expandEnvPairs expands variables in the values of pairs, in place, resolving against lookupEnv
+func expandEnvPairs(pairs []EnvPair, lookupEnv func(string) (string, bool)) (issues []serr.SErr) {
+	e := &envExpander{pairs: pairs, lookupEnv: lookupEnv, state: make([]int, len(pairs))}
+	for i := range pairs {
+		e.expand(i)
+	}
+	return e.issues
+}
+
+func (e *envExpander) expand(i int) {
+	if e.state[i] != expandTodo {
+		return
+	}
+	e.state[i] = expandInProgress
+	if e.pairs[i].Quote != '\'' {
+		e.pairs[i].Value = e.expandString(e.pairs[i].Value, i)
+	}
+	e.state[i] = expandDone
+}
+
+// expandString expands the variables in s, which is part of the value of pair i
+func (e *envExpander) expandString(s string, i int) string {
+	var sb strings.Builder
+	for k := 0; k < len(s); k++ {
+		if s[k] != '$' || k+1 == len(s) {
+			sb.WriteByte(s[k])
+			continue
+		}
+
+		switch next := s[k+1]; {
+		case next == '$':
+			sb.WriteByte('$')
+			k++
+		case next == '{':
+			end := closingBrace(s, k+1)
+			if end == -1 {
+				e.issue("Unterminated '${'", i)
+				sb.WriteString(s[k:])
+				return sb.String()
+			}
+			sb.WriteString(e.expandBraced(s[k+2:end], i))
+			k = end
+		case isEnvNameByte(next, true):
+			j := k + 1
+			for j < len(s) && isEnvNameByte(s[j], false) {
+				j++
+			}
+			sb.WriteString(e.resolve(s[k+1:j], i))
+			k = j - 1
+		default:
+			sb.WriteByte('$')
+		}
+	}
+	return sb.String()
+}
+
+// expandBraced expands the expression between "${" and "}"
+func (e *envExpander) expandBraced(expr string, i int) string {
+	n := 0
+	for n < len(expr) && isEnvNameByte(expr[n], n == 0) {
+		n++
+	}
+	name, rest := expr[:n], expr[n:]
+	if rest == "" && name != "" {
+		return e.resolve(name, i)
+	}
+
+	colon := strings.HasPrefix(rest, ":")
+	op := strings.TrimPrefix(rest, ":")
+	if name == "" || op == "" || !strings.ContainsRune("-?+", rune(op[0])) {
+		e.issue("Bad substitution", i, "expr", "${"+expr+"}")
+		return ""
+	}
+	word := op[1:]
+
+	val, ok := e.lookup(name, i)
+	set := ok && (val != "" || !colon)
+	switch op[0] {
+	case '-':
+		if !set {
+			return e.expandString(word, i)
+		}
+	case '?':
+		if !set {
+			msg := e.expandString(word, i)
+			if msg == "" {
+				msg = "Variable is not set"
+			}
+			e.issue(msg, i, "var", name)
+			return ""
+		}
+	case '+':
+		if set {
+			return e.expandString(word, i)
+		}
+		return ""
+	}
+	return val
+}
+
+// resolve returns the value of variable name for pair i, reporting it if undefined
+func (e *envExpander) resolve(name string, i int) string {
+	val, ok := e.lookup(name, i)
+	if !ok {
+		e.issue("Variable is not defined", i, "var", name)
+	}
+	return val
+}
+
+// lookup returns the value of variable name for pair i: the last earlier key in the file,
+// then the environment, then the first later key. A reference cycle is reported and not found
+func (e *envExpander) lookup(name string, i int) (val string, ok bool) {
+	j := -1
+	for k := i - 1; k >= 0 && j == -1; k-- {
+		if e.pairs[k].Key == name {
+			j = k
+		}
+	}
+	if j == -1 {
+		if val, ok := e.lookupEnv(name); ok {
+			return val, true
+		}
+		for k := i + 1; k < len(e.pairs) && j == -1; k++ {
+			if e.pairs[k].Key == name {
+				j = k
+			}
+		}
+	}
+	if j == -1 {
+		return "", false
+	}
+
+	if e.state[j] == expandInProgress {
+		e.issue("Variable reference cycle", i, "var", name)
+		return "", true
+	}
+	e.expand(j)
+	return e.pairs[j].Value, true
+}
+
+func (e *envExpander) issue(msg string, i int, fields ...string) {
+	fields = append([]string{"key", e.pairs[i].Key, "lineNbr", fmt.Sprintf("%d", e.pairs[i].LineNbr)}, fields...)
+	e.issues = append(e.issues, serr.NewSErr(msg, fields...))
+}
+
+// closingBrace returns the index of the '}' matching the '{' at open, or -1
+func closingBrace(s string, open int) int {
+	depth := 0
+	for k := open; k < len(s); k++ {
+		switch s[k] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return k
+			}
+		}
+	}
+	return -1
+}
+
+func isEnvNameByte(b byte, first bool) bool {
+	return b == '_' || b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z' || !first && b >= '0' && b <= '9'
+}
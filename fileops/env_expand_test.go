@@ -0,0 +1,108 @@
+package fileops
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEnvExpand(t *testing.T) {
+	t.Setenv("RUTIL_HOME", "/home/app")
+	t.Setenv("RUTIL_EMPTY", "")
+
+	tests := []struct {
+		name           string
+		envContent     string
+		expected       map[string]string
+		expectedIssues []string
+	}{
+		{
+			name:       "simple and braced",
+			envContent: "HOST=db\nURL=postgres://$HOST:5432/${RUTIL_HOME}\nPRICE=$$5\n",
+			expected:   map[string]string{"URL": "postgres://db:5432//home/app", "PRICE": "$5"},
+		},
+		{
+			name:       "quoting",
+			envContent: "A=x\nDQ=\"$A y\"\nSQ='$A y'\n",
+			expected:   map[string]string{"DQ": "x y", "SQ": "$A y"},
+		},
+		{
+			name:       "earlier keys win over the environment",
+			envContent: "RUTIL_HOME=/srv\nDIR=${RUTIL_HOME}/data\n",
+			expected:   map[string]string{"DIR": "/srv/data"},
+		},
+		{
+			name:       "self reference uses the environment",
+			envContent: "RUTIL_HOME=$RUTIL_HOME/sub\n",
+			expected:   map[string]string{"RUTIL_HOME": "/home/app/sub"},
+		},
+		{
+			name:       "forward reference",
+			envContent: "URL=http://$HOST\nHOST=web\n",
+			expected:   map[string]string{"URL": "http://web"},
+		},
+		{
+			name: "defaults and alternatives",
+			envContent: "A=${RUTIL_UNSET:-dflt}\nB=${RUTIL_EMPTY:-dflt}\nC=${RUTIL_EMPTY-dflt}\n" +
+				"D=${RUTIL_HOME:+set}\nE=${RUTIL_UNSET:+set}x\nF=${RUTIL_UNSET:-${RUTIL_HOME}/x}\n",
+			expected: map[string]string{"A": "dflt", "B": "dflt", "C": "", "D": "set", "E": "x", "F": "/home/app/x"},
+		},
+		{
+			name:           "required",
+			envContent:     "A=${RUTIL_HOME:?home is needed}\nB=${RUTIL_UNSET:?token is needed}\nC=${RUTIL_EMPTY:?}\n",
+			expected:       map[string]string{"A": "/home/app", "B": "", "C": ""},
+			expectedIssues: []string{"token is needed", "Variable is not set"},
+		},
+		{
+			name:           "undefined",
+			envContent:     "A=x${RUTIL_UNSET}y\n",
+			expected:       map[string]string{"A": "xy"},
+			expectedIssues: []string{"Variable is not defined"},
+		},
+		{
+			name:           "cycle",
+			envContent:     "A=$B\nB=${A}\n",
+			expected:       map[string]string{"A": "", "B": ""},
+			expectedIssues: []string{"Variable reference cycle"},
+		},
+		{
+			name:           "malformed",
+			envContent:     "A=${RUTIL_HOME\nB=${:-x}\nC=cost $5\n",
+			expected:       map[string]string{"A": "${RUTIL_HOME", "B": "", "C": "cost $5"},
+			expectedIssues: []string{"Unterminated '${'", "Bad substitution"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs, issues, err := ParseEnv(strings.NewReader(tt.envContent), EnvOptions{Expand: true})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			got := make(map[string]string, len(pairs))
+			for _, pair := range pairs {
+				got[pair.Key] = pair.Value
+			}
+			for key, expected := range tt.expected {
+				if got[key] != expected {
+					t.Errorf("Expected %s=%q, got %q", key, expected, got[key])
+				}
+			}
+
+			var msgs []string
+			for _, issue := range issues {
+				msgs = append(msgs, issue.Error())
+			}
+			if strings.Join(msgs, "|") != strings.Join(tt.expectedIssues, "|") {
+				t.Errorf("Expected issues %q, got %q", tt.expectedIssues, msgs)
+			}
+		})
+	}
+
+	t.Run("not expanded by default", func(t *testing.T) {
+		pairs, _, _ := ParseEnv(strings.NewReader("A=$RUTIL_HOME\n"))
+		if pairs[0].Value != "$RUTIL_HOME" {
+			t.Errorf("Expected $RUTIL_HOME, got %s", pairs[0].Value)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package fileops
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// EnvCase is the case transform applied to sections and keys in environment variable names
+type EnvCase int
+
+const (
+	EnvCaseUpper    EnvCase = iota // DATABASE__HOST, the default
+	EnvCaseLower                   // database__host
+	EnvCasePreserve                // as written in the ini file
+)
+
+// EnvNaming is a convention for naming the environment variable of an ini key,
+// e.g. the zero value names [database] host with prefix MYAPP as MYAPP_DATABASE__HOST.
+// Characters other than letters and digits in sections and keys become underscores.
+// The prefix is used as given
+type EnvNaming struct {
+	PrefixSeparator string // between prefix and section. Defaults to "_"
+	Separator       string // between section and key. Defaults to "__"
+	Case            EnvCase
+}
+
+// VarName returns the environment variable name for key in section
+func (n EnvNaming) VarName(prefix, section, key string) string {
+	name := n.transform(section) + n.separator() + n.transform(key)
+	if prefix != "" {
+		name = prefix + n.prefixSeparator() + name
+	}
+	return name
+}
+
+// OverlayEnv overrides values in sections, in place, with environment variables named
+// by the naming convention (see EnvNaming), returning the "section::key" keys that were set.
+// Existing sections and keys are matched first; remaining vars with the prefix add new keys,
+// lowercased unless the naming preserves case. Without a prefix no keys are added, as any
+// variable with the separator in its name would match. The default naming is EnvNaming{}
+func OverlayEnv(sections map[string]map[string]string, prefix string, optNaming ...EnvNaming) (overridden []string) {
+	naming := EnvNaming{}
+	if len(optNaming) > 0 {
+		naming = optNaming[0]
+	}
+
+	used := make(map[string]bool, 8)
+	for section, attrs := range sections {
+		for key := range attrs {
+			name := naming.VarName(prefix, section, key)
+			if val, ok := os.LookupEnv(name); ok {
+				attrs[key] = val
+				used[name] = true
+				overridden = append(overridden, section+sectKeySep+key)
+			}
+		}
+	}
+
+	if prefix == "" {
+		sort.Strings(overridden)
+		return
+	}
+	namePrefix := prefix + naming.prefixSeparator()
+
+	for _, kv := range os.Environ() {
+		name, val, _ := strings.Cut(kv, "=")
+		if used[name] || !strings.HasPrefix(name, namePrefix) {
+			continue
+		}
+
+		section, key, fnd := strings.Cut(name[len(namePrefix):], naming.separator())
+		if !fnd || section == "" || key == "" {
+			continue
+		}
+		if naming.Case != EnvCasePreserve {
+			section, key = strings.ToLower(section), strings.ToLower(key)
+		}
+
+		// Prefer an existing section whose name maps to the same env var name
+		for existing := range sections {
+			if naming.transform(existing) == naming.transform(section) {
+				section = existing
+				break
+			}
+		}
+		if sections[section] == nil {
+			sections[section] = make(map[string]string, 4)
+		}
+		sections[section][key] = val
+		overridden = append(overridden, section+sectKeySep+key)
+	}
+
+	sort.Strings(overridden)
+	return
+}
+
+// ExportEnv returns "NAME=value" pairs for every key in sections, sorted by name,
+// using the naming convention (see EnvNaming). The default naming is EnvNaming{}
+func ExportEnv(sections map[string]map[string]string, prefix string, optNaming ...EnvNaming) (pairs []string) {
+	naming := EnvNaming{}
+	if len(optNaming) > 0 {
+		naming = optNaming[0]
+	}
+
+	for section, attrs := range sections {
+		for key, val := range attrs {
+			pairs = append(pairs, naming.VarName(prefix, section, key)+"="+val)
+		}
+	}
+
+	sort.Strings(pairs)
+	return
+}
+
+func (n EnvNaming) separator() string {
+	if n.Separator == "" {
+		return "__"
+	}
+	return n.Separator
+}
+
+func (n EnvNaming) prefixSeparator() string {
+	if n.PrefixSeparator == "" {
+		return "_"
+	}
+	return n.PrefixSeparator
+}
+
+// transform applies the case transform to s, replacing characters other than letters and digits
+func (n EnvNaming) transform(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, s)
+
+	switch n.Case {
+	case EnvCaseUpper:
+		return strings.ToUpper(s)
+	case EnvCaseLower:
+		return strings.ToLower(s)
+	}
+	return s
+}
@@ -0,0 +1,109 @@
+package fileops
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOverlayEnv(t *testing.T) {
+	tests := []struct {
+		name               string
+		env                map[string]string
+		naming             []EnvNaming
+		expectedSections   map[string]map[string]string
+		expectedOverridden []string
+	}{
+		{
+			name: "default naming",
+			env: map[string]string{
+				"MYAPP_DATABASE__HOST": "env-db",
+				"MYAPP_MY_CACHE__TTL":  "5m",
+				"MYAPP_NEW__KEY":       "added",
+				"MYAPP_NOSEPARATOR":    "ignored",
+				"OTHER_DATABASE__HOST": "ignored",
+			},
+			expectedSections: map[string]map[string]string{
+				"database": {"host": "env-db", "port": "5432"},
+				"my-cache": {"ttl": "5m"},
+				"new":      {"key": "added"},
+			},
+			expectedOverridden: []string{"database::host", "my-cache::ttl", "new::key"},
+		},
+		{
+			name:   "custom separators and lower case",
+			env:    map[string]string{"MYAPP.database.port": "6543"},
+			naming: []EnvNaming{{PrefixSeparator: ".", Separator: ".", Case: EnvCaseLower}},
+			expectedSections: map[string]map[string]string{
+				"database": {"host": "localhost", "port": "6543"},
+				"my-cache": {"ttl": "60s"},
+			},
+			expectedOverridden: []string{"database::port"},
+		},
+		{
+			name:   "preserved case adds keys as named",
+			env:    map[string]string{"MYAPP_Extra__MaxConns": "10"},
+			naming: []EnvNaming{{Case: EnvCasePreserve}},
+			expectedSections: map[string]map[string]string{
+				"database": {"host": "localhost", "port": "5432"},
+				"my-cache": {"ttl": "60s"},
+				"Extra":    {"MaxConns": "10"},
+			},
+			expectedOverridden: []string{"Extra::MaxConns"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			sections := map[string]map[string]string{
+				"database": {"host": "localhost", "port": "5432"},
+				"my-cache": {"ttl": "60s"},
+			}
+
+			overridden := OverlayEnv(sections, "MYAPP", tt.naming...)
+
+			if !reflect.DeepEqual(sections, tt.expectedSections) {
+				t.Errorf("Expected %v, got %v", tt.expectedSections, sections)
+			}
+			if !reflect.DeepEqual(overridden, tt.expectedOverridden) {
+				t.Errorf("Expected overridden %v, got %v", tt.expectedOverridden, overridden)
+			}
+		})
+	}
+
+	t.Run("no prefix only overrides existing keys", func(t *testing.T) {
+		t.Setenv("DATABASE__HOST", "env-db")
+		t.Setenv("SOME__THING", "x")
+		sections := map[string]map[string]string{"database": {"host": "localhost"}}
+
+		overridden := OverlayEnv(sections, "")
+
+		expected := map[string]map[string]string{"database": {"host": "env-db"}}
+		if !reflect.DeepEqual(sections, expected) {
+			t.Errorf("Expected %v, got %v", expected, sections)
+		}
+		if !reflect.DeepEqual(overridden, []string{"database::host"}) {
+			t.Errorf("Expected only database::host, got %v", overridden)
+		}
+	})
+}
+
+func TestExportEnv(t *testing.T) {
+	sections := map[string]map[string]string{
+		"database": {"host": "db", "port": "5432"},
+		"my-cache": {"ttl": "60s"},
+	}
+
+	expected := []string{"MYAPP_DATABASE__HOST=db", "MYAPP_DATABASE__PORT=5432", "MYAPP_MY_CACHE__TTL=60s"}
+	if pairs := ExportEnv(sections, "MYAPP"); !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("Expected %v, got %v", expected, pairs)
+	}
+
+	expected = []string{"database_host=db", "database_port=5432", "my_cache_ttl=60s"}
+	pairs := ExportEnv(sections, "", EnvNaming{Separator: "_", Case: EnvCaseLower})
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("Expected %v, got %v", expected, pairs)
+	}
+}
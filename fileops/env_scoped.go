@@ -0,0 +1,68 @@
+package fileops
+
+import (
+	"os"
+	"sync"
+
+	"github.com/go-serr/serr"
+)
+
+// CleanupTB is the part of testing.TB that EnvFromFileForTest uses, so that this package
+// doesn't import testing
+type CleanupTB interface {
+	Helper()
+	Cleanup(func())
+	Fatalf(format string, args ...any)
+}
+
+// EnvFromFileScoped loads a `*.env` style file into the environment as EnvFromFile does,
+// returning restore, which puts back the prior values of the file's variables, unsetting those
+// that were unset. restore is safe to call more than once. If err is not nil nothing was changed
+func EnvFromFileScoped(filespec string, optOpts ...EnvOptions) (restore func(), issues []serr.SErr, err error) {
+	pairs, issues, err := ParseEnvFile(filespec, optOpts...)
+	if err != nil {
+		return func() {}, issues, err
+	}
+
+	type prior struct {
+		val string
+		set bool
+	}
+	priors := make(map[string]prior, len(pairs))
+	for _, pair := range pairs {
+		if _, ok := priors[pair.Key]; !ok {
+			val, set := os.LookupEnv(pair.Key)
+			priors[pair.Key] = prior{val: val, set: set}
+		}
+	}
+
+	_, applyIssues := ApplyEnvPairs(pairs, true)
+	issues = append(issues, applyIssues...)
+
+	var once sync.Once
+	restore = func() {
+		once.Do(func() {
+			for key, p := range priors {
+				if p.set {
+					_ = os.Setenv(key, p.val)
+				} else {
+					_ = os.Unsetenv(key)
+				}
+			}
+		})
+	}
+	return restore, issues, nil
+}
+
+// EnvFromFileForTest loads a `*.env` style file for the duration of test t, restoring the
+// environment with t.Cleanup, and fails the test if the file can't be read. As with t.Setenv,
+// it must not be used in parallel tests
+func EnvFromFileForTest(t CleanupTB, filespec string, optOpts ...EnvOptions) (issues []serr.SErr) {
+	t.Helper()
+	restore, issues, err := EnvFromFileScoped(filespec, optOpts...)
+	if err != nil {
+		t.Fatalf("Error loading %s: %v", filespec, err)
+	}
+	t.Cleanup(restore)
+	return issues
+}
@@ -0,0 +1,78 @@
+package fileops
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvFromFileScoped(t *testing.T) {
+	filespec := filepath.Join(t.TempDir(), "test.env")
+	if err := os.WriteFile(filespec, []byte("SCOPED_SET=new\nSCOPED_UNSET=new\nSCOPED_UNSET=again\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SCOPED_SET", "old")
+	t.Setenv("SCOPED_UNSET", "")
+	os.Unsetenv("SCOPED_UNSET")
+
+	restore, issues, err := EnvFromFileScoped(filespec)
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("Unexpected error %v or issues %v", err, issues)
+	}
+	if os.Getenv("SCOPED_SET") != "new" || os.Getenv("SCOPED_UNSET") != "again" {
+		t.Errorf("Expected the file to be loaded, got %q and %q", os.Getenv("SCOPED_SET"), os.Getenv("SCOPED_UNSET"))
+	}
+
+	restore()
+	restore()
+	if val := os.Getenv("SCOPED_SET"); val != "old" {
+		t.Errorf("Expected SCOPED_SET to be restored to old, got %q", val)
+	}
+	if _, ok := os.LookupEnv("SCOPED_UNSET"); ok {
+		t.Error("Expected SCOPED_UNSET to be unset again")
+	}
+
+	restore, _, err = EnvFromFileScoped(filepath.Join(t.TempDir(), "missing.env"))
+	if err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+	restore()
+}
+
+// fakeTB records what EnvFromFileForTest does with a testing.TB
+type fakeTB struct {
+	cleanups []func()
+	fatal    string
+}
+
+func (f *fakeTB) Helper()           {}
+func (f *fakeTB) Cleanup(fn func()) { f.cleanups = append(f.cleanups, fn) }
+func (f *fakeTB) Fatalf(format string, args ...any) {
+	f.fatal = fmt.Sprintf(format, args...)
+}
+
+func TestEnvFromFileForTest(t *testing.T) {
+	filespec := filepath.Join(t.TempDir(), "test.env")
+	if err := os.WriteFile(filespec, []byte("SCOPED_TB=value\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SCOPED_TB", "")
+	os.Unsetenv("SCOPED_TB")
+
+	t.Run("loads for the test", func(t *testing.T) {
+		EnvFromFileForTest(t, filespec)
+		if val := os.Getenv("SCOPED_TB"); val != "value" {
+			t.Errorf("Expected value, got %q", val)
+		}
+	})
+	if _, ok := os.LookupEnv("SCOPED_TB"); ok {
+		t.Error("Expected SCOPED_TB to be unset after the subtest")
+	}
+
+	tb := &fakeTB{}
+	EnvFromFileForTest(tb, filepath.Join(t.TempDir(), "missing.env"))
+	if tb.fatal == "" {
+		t.Error("Expected a missing file to fail the test")
+	}
+}
@@ -0,0 +1,204 @@
+package fileops
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/go-serr/serr"
+)
+
+var (
+	tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	yamlPlain   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+	yamlSpecial = map[string]bool{"y": true, "yes": true, "n": true, "no": true, "true": true, "false": true,
+		"on": true, "off": true, "null": true}
+)
+
+// IniToJSON renders sections as an indented JSON object of objects, one per section,
+// with sections and keys sorted. Values are kept as strings so nothing is lost
+func IniToJSON(sections map[string]map[string]string) ([]byte, error) {
+	data, err := json.MarshalIndent(sections, "", "  ")
+	if err != nil {
+		return nil, serr.Wrap(err, "Error rendering sections as JSON")
+	}
+	return append(data, '\n'), nil
+}
+
+// JSONToIni reads a JSON object of objects, two levels deep, as sections. Numbers and booleans
+// become their JSON text. Other members, such as nulls, arrays and deeper objects, are skipped
+// and reported as issues. It is an error if data is not a JSON object
+func JSONToIni(data []byte) (sections map[string]map[string]string, issues []serr.SErr, err error) {
+	var top map[string]json.RawMessage
+	if err = json.Unmarshal(data, &top); err != nil {
+		return nil, issues, serr.Wrap(err, "Error reading JSON, expected an object of sections")
+	}
+
+	sections = make(map[string]map[string]string, len(top))
+	for _, section := range sortedKeys(top) {
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(top[section], &attrs); err != nil || attrs == nil {
+			issues = append(issues, serr.NewSErr("Section is not an object", "section", section))
+			continue
+		}
+
+		sections[section] = make(map[string]string, len(attrs))
+		for _, key := range sortedKeys(attrs) {
+			raw := bytes.TrimSpace(attrs[key])
+			var val string
+			switch {
+			case len(raw) > 0 && raw[0] == '"':
+				_ = json.Unmarshal(raw, &val)
+			case len(raw) > 0 && (raw[0] == '-' || raw[0] >= '0' && raw[0] <= '9'),
+				string(raw) == "true", string(raw) == "false":
+				val = string(raw)
+			default:
+				issues = append(issues, serr.NewSErr("Value is not a string, number or boolean",
+					"section", section, "key", key))
+				continue
+			}
+			sections[section][key] = val
+		}
+	}
+	return
+}
+
+// ReadJSONAsMapOfSections reads a JSON file as JSONToIni does
+func ReadJSONAsMapOfSections(filespec string) (sections map[string]map[string]string, issues []serr.SErr, err error) {
+	data, err := os.ReadFile(filespec)
+	if err != nil {
+		return nil, issues, serr.Wrap(err, "Error reading: "+filespec)
+	}
+	sections, issues, err = JSONToIni(data)
+	if err != nil {
+		return sections, issues, serr.Wrap(err, "file", filespec)
+	}
+	return
+}
+
+// SectionsToIni renders sections as an ini file, sorted, quoting values as the readers need.
+// Sections, keys and values the readers can't read back, such as empty values or values with
+// newlines, are skipped and reported as issues
+func SectionsToIni(sections map[string]map[string]string) (ini []byte, issues []serr.SErr) {
+	doc := &IniDoc{}
+	for _, section := range sortedKeys(sections) {
+		if !iniSectionReadsBack(section) {
+			issues = append(issues, serr.NewSErr("Section name can't be written to an ini file, skipped",
+				"section", section))
+			continue
+		}
+		if n := len(doc.Lines); n > 0 {
+			doc.Lines = append(doc.Lines, IniLine{Kind: LineBlank, Section: doc.Lines[n-1].Section})
+		}
+		doc.Lines = append(doc.Lines, IniLine{Kind: LineSection, Section: section, Raw: "[" + section + "]"})
+
+		for _, key := range sortedKeys(sections[section]) {
+			if sections[section][key] == "" {
+				issues = append(issues, serr.NewSErr("Value is empty, which the readers skip, skipped",
+					"section", section, "key", key))
+				continue
+			}
+			if err := doc.Set(section, key, sections[section][key]); err != nil {
+				issues = append(issues, serr.SErrFromErr(err))
+			}
+		}
+	}
+	return []byte(doc.String()), issues
+}
+
+// IniToEnv renders sections as a .env file of `SECTION_KEY=value` lines, sorted, quoting values
+// as WriteEnv does so that EnvFromFile and shells read them back unchanged, without expanding '$'.
+// The default naming is EnvNaming{Separator: "_"}, as Loader uses. Keys whose names collide and
+// values that can't be read back, such as empty values, are skipped and reported as issues
+func IniToEnv(sections map[string]map[string]string, prefix string, optNaming ...EnvNaming) (env []byte, issues []serr.SErr) {
+	naming := EnvNaming{Separator: "_"}
+	if len(optNaming) > 0 {
+		naming = optNaming[0]
+	}
+
+	lines := make(map[string]string, 16)
+	from := make(map[string]string, 16)
+	for _, section := range sortedKeys(sections) {
+		for _, key := range sortedKeys(sections[section]) {
+			val := sections[section][key]
+			name := naming.VarName(prefix, section, key)
+			if other, ok := from[name]; ok {
+				issues = append(issues, serr.NewSErr("Variable name collides with "+other,
+					"section", section, "key", key, "name", name))
+				continue
+			}
+			line, ok := envLine(name, val)
+			if !ok {
+				issues = append(issues, serr.NewSErr("Value can't be written to a .env file, skipped",
+					"section", section, "key", key, "name", name))
+				continue
+			}
+			from[name] = section + sectKeySep + key
+			lines[name] = line
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, name := range sortedKeys(lines) {
+		buf.WriteString(lines[name] + "\n")
+	}
+	return buf.Bytes(), issues
+}
+
+// IniToTOML renders sections as TOML tables of string values, sorted
+func IniToTOML(sections map[string]map[string]string) []byte {
+	var buf bytes.Buffer
+	for i, section := range sortedKeys(sections) {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString("[" + tomlKey(section) + "]\n")
+		for _, key := range sortedKeys(sections[section]) {
+			buf.WriteString(tomlKey(key) + " = " + jsonQuote(sections[section][key]) + "\n")
+		}
+	}
+	return buf.Bytes()
+}
+
+// IniToYAML renders sections as a YAML mapping of mappings, sorted. Values are always double
+// quoted, so that YAML doesn't read values such as "no" or "1.0" as other types
+func IniToYAML(sections map[string]map[string]string) []byte {
+	var buf bytes.Buffer
+	for _, section := range sortedKeys(sections) {
+		if len(sections[section]) == 0 {
+			buf.WriteString(yamlKey(section) + ": {}\n")
+			continue
+		}
+		buf.WriteString(yamlKey(section) + ":\n")
+		for _, key := range sortedKeys(sections[section]) {
+			buf.WriteString(fmt.Sprintf("  %s: %s\n", yamlKey(key), jsonQuote(sections[section][key])))
+		}
+	}
+	return buf.Bytes()
+}
+
+// jsonQuote returns val as a JSON string, which is also a valid TOML basic string and YAML double quoted scalar
+func jsonQuote(val string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(val)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func tomlKey(key string) string {
+	if tomlBareKey.MatchString(key) {
+		return key
+	}
+	return jsonQuote(key)
+}
+
+func yamlKey(key string) string {
+	if yamlPlain.MatchString(key) && !yamlSpecial[strings.ToLower(key)] {
+		return key
+	}
+	return jsonQuote(key)
+}
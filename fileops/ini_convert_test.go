@@ -0,0 +1,153 @@
+package fileops
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIniConversions(t *testing.T) {
+	sections := map[string]map[string]string{
+		"database": {"host": "localhost", "port": "5432", "password": `"secret" # really`},
+		"feature":  {"on": "no", "ratio": "1.0"},
+		"my.app":   {"log level": " padded "},
+	}
+
+	t.Run("json round trip", func(t *testing.T) {
+		data, err := IniToJSON(sections)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got, issues, err := JSONToIni(data)
+		if err != nil || len(issues) != 0 {
+			t.Fatalf("Unexpected error %v or issues %v", err, issues)
+		}
+		if !reflect.DeepEqual(got, sections) {
+			t.Errorf("Expected %v, got %v", sections, got)
+		}
+	})
+
+	t.Run("json types", func(t *testing.T) {
+		got, issues, err := JSONToIni([]byte(`{"db": {"port": 5432, "tls": true, "ratio": -0.5, "tags": ["a"], "x": null}, "name": "app"}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := map[string]map[string]string{"db": {"port": "5432", "tls": "true", "ratio": "-0.5"}}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected %v, got %v", expected, got)
+		}
+		if len(issues) != 3 {
+			t.Errorf("Expected 3 issues, got %d: %v", len(issues), issues)
+		}
+		if _, _, err := JSONToIni([]byte(`[1, 2]`)); err == nil {
+			t.Error("Expected an error for a JSON array")
+		}
+	})
+
+	t.Run("ini round trip", func(t *testing.T) {
+		ini, issues := SectionsToIni(sections)
+		if len(issues) != 0 {
+			t.Fatalf("Unexpected issues %v", issues)
+		}
+		filespec := filepath.Join(t.TempDir(), "app.ini")
+		if err := os.WriteFile(filespec, ini, 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, _, err := ReadIniAsMapOfSections(filespec)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, sections) {
+			t.Errorf("Expected %v, got %v\n%s", sections, got, ini)
+		}
+
+		_, issues = SectionsToIni(map[string]map[string]string{"s": {"k": "two\nlines", "q": ` it's "x"`, "e": ""}})
+		if len(issues) != 3 {
+			t.Errorf("Expected issues for values that can't be read back, got %v", issues)
+		}
+	})
+
+	t.Run("env", func(t *testing.T) {
+		env, issues := IniToEnv(sections, "APP")
+		if len(issues) != 0 {
+			t.Fatalf("Unexpected issues %v", issues)
+		}
+		expected := `APP_DATABASE_HOST=localhost
+APP_DATABASE_PASSWORD='"secret" # really'
+APP_DATABASE_PORT=5432
+APP_FEATURE_ON=no
+APP_FEATURE_RATIO=1.0
+APP_MY_APP_LOG_LEVEL=' padded '
+`
+		if string(env) != expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", expected, env)
+		}
+
+		filespec := filepath.Join(t.TempDir(), ".env")
+		if err := os.WriteFile(filespec, env, 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(env)), "\n") {
+			name, _, _ := strings.Cut(line, "=")
+			t.Setenv(name, "")
+		}
+		if _, err := EnvFromFile(filespec); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := os.Getenv("APP_DATABASE_PASSWORD"); got != sections["database"]["password"] {
+			t.Errorf("Expected %q, got %q", sections["database"]["password"], got)
+		}
+		if got := os.Getenv("APP_MY_APP_LOG_LEVEL"); got != " padded " {
+			t.Errorf("Expected %q, got %q", " padded ", got)
+		}
+
+		_, issues = IniToEnv(map[string]map[string]string{"a": {"b_c": "1"}, "a_b": {"c": "2"}}, "")
+		if len(issues) != 1 {
+			t.Errorf("Expected a collision issue, got %v", issues)
+		}
+
+		env, issues = IniToEnv(map[string]map[string]string{"a": {"home": "$HOME/x", "empty": ""}}, "")
+		if string(env) != "A_HOME='$HOME/x'\n" {
+			t.Errorf("Expected $HOME to be single quoted, got %q", env)
+		}
+		if len(issues) != 1 || issues[0].FieldsMap()["key"] != "empty" {
+			t.Errorf("Expected an issue for the empty value, got %v", issues)
+		}
+	})
+
+	t.Run("toml", func(t *testing.T) {
+		expected := `[database]
+host = "localhost"
+password = "\"secret\" # really"
+port = "5432"
+
+[feature]
+on = "no"
+ratio = "1.0"
+
+["my.app"]
+"log level" = " padded "
+`
+		if got := string(IniToTOML(sections)); got != expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+		}
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		expected := `database:
+  host: "localhost"
+  password: "\"secret\" # really"
+  port: "5432"
+feature:
+  "on": "no"
+  ratio: "1.0"
+my.app:
+  "log level": " padded "
+`
+		if got := string(IniToYAML(sections)); got != expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+		}
+	})
+}
@@ -0,0 +1,196 @@
+package fileops
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-serr/serr"
+)
+
+// DiffKind is the kind of difference between two ini files
+type DiffKind string
+
+const (
+	DiffAdded   DiffKind = "added"
+	DiffRemoved DiffKind = "removed"
+	DiffChanged DiffKind = "changed"
+)
+
+// SectionDiff is a section present in only one of two ini files
+type SectionDiff struct {
+	Section    string   `json:"section"`
+	Kind       DiffKind `json:"kind"`
+	OldLineNbr int      `json:"oldLineNbr,omitempty"`
+	NewLineNbr int      `json:"newLineNbr,omitempty"`
+}
+
+// KeyDiff is a key added, removed or changed between two ini files
+type KeyDiff struct {
+	Section    string   `json:"section"`
+	Key        string   `json:"key"`
+	Kind       DiffKind `json:"kind"`
+	Old        string   `json:"old,omitempty"`
+	New        string   `json:"new,omitempty"`
+	OldLineNbr int      `json:"oldLineNbr,omitempty"`
+	NewLineNbr int      `json:"newLineNbr,omitempty"`
+}
+
+// IniDiff holds the differences between two ini files, sorted by section then key.
+// Keys of added and removed sections are included in Keys
+type IniDiff struct {
+	Sections []SectionDiff `json:"sections"`
+	Keys     []KeyDiff     `json:"keys"`
+}
+
+// DiffIni returns the differences from a to b. Positions for a then b may be given
+// (as from ReadIniWithPositions) to fill in line numbers
+func DiffIni(a, b map[string]map[string]string, optPositions ...Positions) (diff IniDiff) {
+	var posA, posB Positions
+	if len(optPositions) > 0 {
+		posA = optPositions[0]
+	}
+	if len(optPositions) > 1 {
+		posB = optPositions[1]
+	}
+
+	diff.Sections, diff.Keys = []SectionDiff{}, []KeyDiff{}
+
+	for _, section := range sortedKeys(unionKeys(a, b)) {
+		attrsA, inA := a[section]
+		attrsB, inB := b[section]
+		if !inA {
+			diff.Sections = append(diff.Sections, SectionDiff{Section: section, Kind: DiffAdded,
+				NewLineNbr: posB.OfSection(section)})
+		} else if !inB {
+			diff.Sections = append(diff.Sections, SectionDiff{Section: section, Kind: DiffRemoved,
+				OldLineNbr: posA.OfSection(section)})
+		}
+
+		for _, key := range sortedKeys(unionKeys(attrsA, attrsB)) {
+			valA, keyInA := attrsA[key]
+			valB, keyInB := attrsB[key]
+
+			kd := KeyDiff{Section: section, Key: key, Old: valA, New: valB,
+				OldLineNbr: posA.Of(section, key), NewLineNbr: posB.Of(section, key)}
+			switch {
+			case !keyInA:
+				kd.Kind = DiffAdded
+			case !keyInB:
+				kd.Kind = DiffRemoved
+			case valA != valB:
+				kd.Kind = DiffChanged
+			default:
+				continue
+			}
+			diff.Keys = append(diff.Keys, kd)
+		}
+	}
+	return
+}
+
+// DiffIniFiles reads two ini files returning the differences from fileA to fileB
+func DiffIniFiles(fileA, fileB string) (diff IniDiff, issues []serr.SErr, err error) {
+	a, posA, issues, err := ReadIniWithPositions(fileA)
+	if err != nil {
+		return diff, issues, err
+	}
+
+	b, posB, issuesB, err := ReadIniWithPositions(fileB)
+	issues = append(issues, issuesB...)
+	if err != nil {
+		return diff, issues, err
+	}
+
+	return DiffIni(a, b, posA, posB), issues, nil
+}
+
+// Empty reports whether there are no differences
+func (d IniDiff) Empty() bool {
+	return len(d.Sections) == 0 && len(d.Keys) == 0
+}
+
+// Unified renders the differences in the style of a unified diff, e.g.
+//
+//	--- old.ini
+//	+++ new.ini
+//	@@ [database] @@
+//	-host = old-db
+//	+host = new-db
+//	+[cache]
+//	+ttl = 60s
+func (d IniDiff) Unified(nameA, nameB string) string {
+	if d.Empty() {
+		return ""
+	}
+
+	sectionKinds := make(map[string]DiffKind, len(d.Sections))
+	for _, sd := range d.Sections {
+		sectionKinds[sd.Section] = sd.Kind
+	}
+
+	var sb strings.Builder
+	sb.WriteString("--- " + nameA + "\n+++ " + nameB + "\n")
+
+	currSection, started := "", false
+	startSection := func(section string) {
+		if started && section == currSection {
+			return
+		}
+		currSection, started = section, true
+		switch sectionKinds[section] {
+		case DiffAdded:
+			sb.WriteString("+[" + section + "]\n")
+		case DiffRemoved:
+			sb.WriteString("-[" + section + "]\n")
+		default:
+			sb.WriteString("@@ [" + section + "] @@\n")
+		}
+	}
+
+	keyIdx := 0
+	for _, sd := range d.Sections {
+		// Sections without keys still need their header, in section order
+		for keyIdx < len(d.Keys) && d.Keys[keyIdx].Section < sd.Section {
+			writeKeyDiff(&sb, d.Keys[keyIdx], startSection)
+			keyIdx++
+		}
+		startSection(sd.Section)
+	}
+	for ; keyIdx < len(d.Keys); keyIdx++ {
+		writeKeyDiff(&sb, d.Keys[keyIdx], startSection)
+	}
+
+	return sb.String()
+}
+
+func writeKeyDiff(sb *strings.Builder, kd KeyDiff, startSection func(string)) {
+	startSection(kd.Section)
+	if kd.Kind != DiffAdded {
+		fmt.Fprintf(sb, "-%s = %s\n", kd.Key, kd.Old)
+	}
+	if kd.Kind != DiffRemoved {
+		fmt.Fprintf(sb, "+%s = %s\n", kd.Key, kd.New)
+	}
+}
+
+// JSON renders the differences as indented JSON
+func (d IniDiff) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, serr.Wrap(err, "Error rendering diff as JSON")
+	}
+	return data, nil
+}
+
+// unionKeys returns a set of the keys of a and b
+func unionKeys[V any](a, b map[string]V) map[string]bool {
+	keys := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	return keys
+}
@@ -0,0 +1,103 @@
+package fileops
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiffIni(t *testing.T) {
+	dir := t.TempDir()
+	fileA := filepath.Join(dir, "old.ini")
+	fileB := filepath.Join(dir, "new.ini")
+	if err := os.WriteFile(fileA, []byte(`[cache]
+ttl = 60s
+
+[database]
+host = old-db
+port = 5432
+user = app
+
+[legacy]
+flag = on
+`), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(fileB, []byte(`[database]
+host = new-db
+port = 5432
+sslmode = require
+
+[cache]
+ttl = 60s
+
+[empty]
+`), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	diff, issues, err := DiffIniFiles(fileA, fileB)
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("Unexpected err: %v, issues: %v", err, issues)
+	}
+
+	expected := IniDiff{
+		Sections: []SectionDiff{
+			{Section: "empty", Kind: DiffAdded, NewLineNbr: 9},
+			{Section: "legacy", Kind: DiffRemoved, OldLineNbr: 9},
+		},
+		Keys: []KeyDiff{
+			{Section: "database", Key: "host", Kind: DiffChanged, Old: "old-db", New: "new-db", OldLineNbr: 5, NewLineNbr: 2},
+			{Section: "database", Key: "sslmode", Kind: DiffAdded, New: "require", NewLineNbr: 4},
+			{Section: "database", Key: "user", Kind: DiffRemoved, Old: "app", OldLineNbr: 7},
+			{Section: "legacy", Key: "flag", Kind: DiffRemoved, Old: "on", OldLineNbr: 10},
+		},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Fatalf("Expected %+v\ngot %+v", expected, diff)
+	}
+
+	t.Run("unified", func(t *testing.T) {
+		expected := `--- old.ini
++++ new.ini
+@@ [database] @@
+-host = old-db
++host = new-db
++sslmode = require
+-user = app
++[empty]
+-[legacy]
+-flag = on
+`
+		if got := diff.Unified("old.ini", "new.ini"); got != expected {
+			t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		data, err := diff.JSON()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var roundTrip IniDiff
+		if err := json.Unmarshal(data, &roundTrip); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(roundTrip, diff) {
+			t.Errorf("Expected %+v, got %+v", diff, roundTrip)
+		}
+	})
+
+	t.Run("no differences", func(t *testing.T) {
+		same := map[string]map[string]string{"a": {"b": "c"}}
+		diff := DiffIni(same, same)
+		if !diff.Empty() || diff.Unified("a", "b") != "" {
+			t.Errorf("Expected empty diff, got %+v", diff)
+		}
+		if data, _ := diff.JSON(); string(data) != "{\n  \"sections\": [],\n  \"keys\": []\n}" {
+			t.Errorf("Unexpected JSON %s", data)
+		}
+	})
+}
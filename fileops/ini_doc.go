@@ -0,0 +1,396 @@
+package fileops
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-serr/serr"
+)
+
+// IniLineKind is the kind of a line in an IniDoc
+type IniLineKind int
+
+const (
+	LineBlank    IniLineKind = iota
+	LineComment              // starts with '#'
+	LineSection              // [section]
+	LineKeyValue             // key = value
+	LineOther                // anything else, which the readers ignore
+)
+
+// IniLine is a line of an ini file. Raw is kept so that unchanged lines are written back as read
+type IniLine struct {
+	Kind    IniLineKind
+	Raw     string // the line as read, or as rebuilt after a change
+	LineNbr int    // line number as read. Zero for lines added since
+	Section string // the section the line is in, including comment lines directly above its header
+	Key     string
+	Value   string // unquoted value
+	Quote   byte   // the quote character the value was written with, if any
+	Comment string // for key lines, any text after the value, e.g. "# comment"
+}
+
+// IniDoc is an ini file as a list of lines, preserving comments, blank lines and order
+// so that it can be edited and written back with minimal changes
+type IniDoc struct {
+	Lines []IniLine
+}
+
+// ParseIniDoc reads an ini document from r. Unlike the map readers, keys before any section
+// are kept (in section "") and reported as issues rather than as an error
+func ParseIniDoc(r io.Reader) (doc *IniDoc, issues []serr.SErr, err error) {
+	doc = &IniDoc{}
+	currSection := ""
+
+	scanner := bufio.NewScanner(r)
+	lineNbr := 0
+	for scanner.Scan() {
+		lineNbr++
+		line := parseIniLine(scanner.Text(), currSection)
+		line.LineNbr = lineNbr
+
+		switch line.Kind {
+		case LineSection:
+			currSection = line.Section
+			for i := doc.commentsAbove(len(doc.Lines)); i < len(doc.Lines); i++ {
+				doc.Lines[i].Section = currSection // comments directly above a header describe it
+			}
+		case LineKeyValue:
+			if line.Key == "" {
+				issues = append(issues, serr.NewSErr("key is empty", "line", line.Raw,
+					"lineNbr", fmt.Sprintf("%d", lineNbr)))
+			} else if currSection == "" {
+				issues = append(issues, serr.NewSErr("Missing section header", "line", line.Raw,
+					"lineNbr", fmt.Sprintf("%d", lineNbr)))
+			}
+		case LineOther:
+			if strings.HasPrefix(strings.TrimSpace(line.Raw), "[") {
+				issues = append(issues, serr.NewSErr("Mismatched '['  ']' or empty section", "line", line.Raw,
+					"lineNbr", fmt.Sprintf("%d", lineNbr)))
+			}
+		}
+		doc.Lines = append(doc.Lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return doc, issues, serr.Wrap(err, "Error while scanning ini document")
+	}
+	return
+}
+
+// ReadIniDoc reads an ini document from a file
+func ReadIniDoc(filespec string) (doc *IniDoc, issues []serr.SErr, err error) {
+	file, err := os.Open(filespec)
+	if err != nil {
+		return &IniDoc{}, issues, serr.Wrap(err, "Error reading: "+filespec)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	doc, issues, err = ParseIniDoc(file)
+	if err != nil {
+		return doc, issues, serr.Wrap(err, "file", filespec)
+	}
+	return
+}
+
+// parseIniLine classifies raw, which is in currSection
+func parseIniLine(raw, currSection string) (line IniLine) {
+	line.Raw = raw
+	line.Section = currSection
+	trimmed := strings.TrimSpace(raw)
+
+	switch {
+	case trimmed == "":
+		line.Kind = LineBlank
+	case strings.HasPrefix(trimmed, "#"):
+		line.Kind = LineComment
+	case strings.HasPrefix(trimmed, "["):
+		b, _, f := strings.Cut(trimmed, "]")
+		if !f || len(b) <= 1 {
+			line.Kind = LineOther
+			return
+		}
+		line.Kind = LineSection
+		line.Section = b[1:]
+	default:
+		bef, aft, fnd := strings.Cut(trimmed, "=")
+		if !fnd {
+			line.Kind = LineOther
+			return
+		}
+		line.Kind = LineKeyValue
+		line.Key = strings.TrimSpace(bef)
+		line.Value, line.Quote, line.Comment = splitIniValue(strings.TrimSpace(aft))
+	}
+	return
+}
+
+// splitIniValue splits a trimmed raw value into its unquoted value, quote character and
+// any trailing text, following the same rules as unquoteValue
+func splitIniValue(val string) (value string, quote byte, rest string) {
+	if len(val) > 1 && (val[0] == '\'' || val[0] == '"') {
+		if idx := strings.IndexByte(val[1:], val[0]); idx != -1 {
+			return val[1 : idx+1], val[0], strings.TrimSpace(val[idx+2:])
+		}
+	} else if x := strings.IndexByte(val, '#'); x != -1 && len(val) > 1 {
+		return strings.TrimSpace(val[:x]), 0, val[x:]
+	}
+	return val, 0, ""
+}
+
+// String returns the document as text, each line ending in a newline
+func (d *IniDoc) String() string {
+	var sb strings.Builder
+	for _, line := range d.Lines {
+		sb.WriteString(line.Raw)
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// WriteFile writes the document to filespec, keeping the permissions of an existing file
+func (d *IniDoc) WriteFile(filespec string) error {
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(filespec); err == nil {
+		perm = info.Mode().Perm()
+	}
+	if err := os.WriteFile(filespec, []byte(d.String()), perm); err != nil {
+		return serr.Wrap(err, "Error writing: "+filespec)
+	}
+	return nil
+}
+
+// Clone returns a copy of the document which can be changed independently
+func (d *IniDoc) Clone() *IniDoc {
+	return &IniDoc{Lines: append([]IniLine{}, d.Lines...)}
+}
+
+// Sections returns the section names in the order they first appear
+func (d *IniDoc) Sections() (sections []string) {
+	seen := make(map[string]bool, 8)
+	for _, line := range d.Lines {
+		if line.Kind == LineSection && !seen[line.Section] {
+			seen[line.Section] = true
+			sections = append(sections, line.Section)
+		}
+	}
+	return
+}
+
+// Get returns the value of key in section. As with the readers, the last occurrence wins
+func (d *IniDoc) Get(section, key string) (val string, ok bool) {
+	if idx := d.lastKeyLine(section, key); idx != -1 {
+		return d.Lines[idx].Value, true
+	}
+	return "", false
+}
+
+// Set sets key in section to val, keeping any comment on the line. A new key is added after
+// the last key of the section, and a new section is added at the end of the document.
+// The document is left unchanged and an error returned if the section, key or value can't be
+// written so that it reads back unchanged, e.g. a value with a newline or with both quote characters and '#'
+func (d *IniDoc) Set(section, key, val string) error {
+	switch {
+	case !iniSectionReadsBack(section):
+		return serr.New("Section name can't be written to an ini file", "section", section)
+	case !iniKeyReadsBack(key):
+		return serr.New("Key can't be written to an ini file", "section", section, "key", key)
+	case !iniValueReadsBack(val):
+		return serr.New("Value can't be written to an ini file", "section", section, "key", key)
+	}
+
+	if idx := d.lastKeyLine(section, key); idx != -1 {
+		line := &d.Lines[idx]
+		indent := line.Raw[:len(line.Raw)-len(strings.TrimLeft(line.Raw, " \t"))]
+		line.Value = val
+		line.Raw = indent + formatIniKeyValue(key, val, line.Quote, line.Comment)
+		return nil
+	}
+
+	line := IniLine{Kind: LineKeyValue, Section: section, Key: key, Value: val,
+		Raw: formatIniKeyValue(key, val, 0, "")}
+
+	insertAt := -1
+	for i, l := range d.Lines {
+		if l.Section == section && (l.Kind == LineSection || l.Kind == LineKeyValue) {
+			insertAt = i + 1
+		}
+	}
+	if insertAt == -1 {
+		if n := len(d.Lines); n > 0 && d.Lines[n-1].Kind != LineBlank {
+			d.Lines = append(d.Lines, IniLine{Kind: LineBlank, Section: d.Lines[n-1].Section})
+		}
+		d.Lines = append(d.Lines, IniLine{Kind: LineSection, Section: section, Raw: "[" + section + "]"}, line)
+		return nil
+	}
+	d.Lines = append(d.Lines[:insertAt], append([]IniLine{line}, d.Lines[insertAt:]...)...)
+	return nil
+}
+
+// Delete removes every occurrence of key in section, returning whether any was found
+func (d *IniDoc) Delete(section, key string) (found bool) {
+	lines := d.Lines[:0]
+	for _, line := range d.Lines {
+		if line.Kind == LineKeyValue && line.Section == section && line.Key == key {
+			found = true
+			continue
+		}
+		lines = append(lines, line)
+	}
+	d.Lines = lines
+	return
+}
+
+// DeleteSection removes every occurrence of section, the lines in it and any comment lines
+// directly above its headers, returning whether any was found
+func (d *IniDoc) DeleteSection(section string) (found bool) {
+	remove := make([]bool, len(d.Lines))
+	for i, line := range d.Lines {
+		if line.Section != section {
+			continue
+		}
+		remove[i] = true
+		if line.Kind == LineSection {
+			found = true
+			for j := d.commentsAbove(i); j < i; j++ {
+				remove[j] = true
+			}
+		}
+	}
+
+	lines := d.Lines[:0]
+	for i, line := range d.Lines {
+		if !remove[i] {
+			lines = append(lines, line)
+		}
+	}
+	d.Lines = lines
+	return
+}
+
+// SectionLines returns the lines of the first occurrence of section, from any comment lines
+// directly above its header up to the next section
+func (d *IniDoc) SectionLines(section string) (lines []IniLine) {
+	for i, line := range d.Lines {
+		if line.Kind == LineSection && line.Section == section {
+			lines = append(lines, d.Lines[d.commentsAbove(i):i+1]...)
+			for _, l := range d.Lines[i+1:] {
+				if l.Kind == LineSection {
+					break
+				}
+				lines = append(lines, l)
+			}
+			return
+		}
+	}
+	return
+}
+
+// commentsAbove returns the index of the first of the comment lines directly above line idx
+func (d *IniDoc) commentsAbove(idx int) int {
+	for idx > 0 && d.Lines[idx-1].Kind == LineComment {
+		idx--
+	}
+	return idx
+}
+
+// AsMapOfSections returns the document's values as ReadIniAsMapOfSections would.
+// Keys before any section and empty values are left out
+func (d *IniDoc) AsMapOfSections() map[string]map[string]string {
+	attrsBySection := make(map[string]map[string]string, 4)
+	for _, line := range d.Lines {
+		switch {
+		case line.Kind == LineSection:
+			if attrsBySection[line.Section] == nil {
+				attrsBySection[line.Section] = make(map[string]string, 4)
+			}
+		case line.Kind == LineKeyValue && line.Section != "" && line.Key != "" && line.Value != "":
+			attrsBySection[line.Section][line.Key] = line.Value
+		}
+	}
+	return attrsBySection
+}
+
+// Positions returns the line numbers of sections and keys as read, as ReadIniWithPositions would
+func (d *IniDoc) Positions() Positions {
+	positions := make(Positions, len(d.Lines))
+	for _, line := range d.Lines {
+		if line.LineNbr == 0 {
+			continue
+		}
+		switch {
+		case line.Kind == LineSection:
+			if _, ok := positions[line.Section]; !ok {
+				positions[line.Section] = line.LineNbr
+			}
+		case line.Kind == LineKeyValue && line.Section != "" && line.Key != "" && line.Value != "":
+			positions[line.Section+sectKeySep+line.Key] = line.LineNbr
+		}
+	}
+	return positions
+}
+
+func (d *IniDoc) lastKeyLine(section, key string) int {
+	for i := len(d.Lines) - 1; i >= 0; i-- {
+		line := d.Lines[i]
+		if line.Kind == LineKeyValue && line.Section == section && line.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// formatIniKeyValue builds a `key = value` line, quoting the value with quote or as needed
+func formatIniKeyValue(key, val string, quote byte, comment string) string {
+	out := key + " = " + quoteIniValue(val, quote)
+	if comment != "" {
+		out += " " + comment
+	}
+	return out
+}
+
+// quoteIniValue returns val quoted with quote if given, or only if the readers would otherwise
+// change it, preferring double quotes. Quotes are kept only if val doesn't contain them
+func quoteIniValue(val string, quote byte) string {
+	if quote != 0 && strings.IndexByte(val, quote) == -1 {
+		return string(quote) + val + string(quote)
+	}
+
+	needsQuotes := val != strings.TrimSpace(val) || strings.ContainsRune(val, '#') ||
+		strings.HasPrefix(val, `"`) || strings.HasPrefix(val, `'`)
+	if !needsQuotes {
+		return val
+	}
+	if !strings.ContainsRune(val, '"') {
+		return `"` + val + `"`
+	}
+	if !strings.ContainsRune(val, '\'') {
+		return `'` + val + `'`
+	}
+	return val // not representable, which callers rule out with iniValueReadsBack
+}
+
+// iniValueReadsBack reports whether val, written as quoteIniValue does, reads back unchanged
+func iniValueReadsBack(val string) bool {
+	if strings.ContainsAny(val, "\r\n") {
+		return false
+	}
+	read, _, _ := splitIniValue(strings.TrimSpace(quoteIniValue(val, 0)))
+	return read == val
+}
+
+// iniSectionReadsBack reports whether section, written as a header, reads back unchanged
+func iniSectionReadsBack(section string) bool {
+	return section != "" && !strings.ContainsAny(section, "]\r\n")
+}
+
+// iniKeyReadsBack reports whether key, written at the start of a key line, reads back unchanged
+func iniKeyReadsBack(key string) bool {
+	return key != "" && key == strings.TrimSpace(key) && !strings.ContainsAny(key, "=\r\n") &&
+		!strings.HasPrefix(key, "#") && !strings.HasPrefix(key, "[")
+}
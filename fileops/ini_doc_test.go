@@ -0,0 +1,190 @@
+package fileops
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIniDoc(t *testing.T) {
+	content := `# App config
+[database]
+host = db   # primary
+  port=5432
+name = "my db"
+
+# Cache settings
+[cache]
+ttl = 60s
+`
+
+	parse := func(t *testing.T) *IniDoc {
+		doc, issues, err := ParseIniDoc(strings.NewReader(content))
+		if err != nil || len(issues) != 0 {
+			t.Fatalf("Unexpected err: %v, issues: %v", err, issues)
+		}
+		return doc
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		if got := parse(t).String(); got != content {
+			t.Errorf("Expected:\n%s\nGot:\n%s", content, got)
+		}
+	})
+
+	t.Run("maps and positions", func(t *testing.T) {
+		doc := parse(t)
+		expected := map[string]map[string]string{
+			"database": {"host": "db", "port": "5432", "name": "my db"},
+			"cache":    {"ttl": "60s"},
+		}
+		if got := doc.AsMapOfSections(); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected %v, got %v", expected, got)
+		}
+
+		expectedPositions := Positions{"database": 2, "database::host": 3, "database::port": 4,
+			"database::name": 5, "cache": 8, "cache::ttl": 9}
+		if got := doc.Positions(); !reflect.DeepEqual(got, expectedPositions) {
+			t.Errorf("Expected %v, got %v", expectedPositions, got)
+		}
+
+		if got := doc.Sections(); !reflect.DeepEqual(got, []string{"database", "cache"}) {
+			t.Errorf("Unexpected sections %v", got)
+		}
+		if line := doc.Lines[2]; line.Comment != "# primary" || line.Key != "host" {
+			t.Errorf("Unexpected line %+v", line)
+		}
+	})
+
+	t.Run("set existing keeps comment, indent and quotes", func(t *testing.T) {
+		doc := parse(t)
+		doc.Set("database", "host", "db2")
+		doc.Set("database", "port", "6543")
+		doc.Set("database", "name", "other db")
+
+		expected := `# App config
+[database]
+host = db2 # primary
+  port = 6543
+name = "other db"
+`
+		if got := doc.String(); !strings.HasPrefix(got, expected) {
+			t.Errorf("Expected prefix:\n%s\nGot:\n%s", expected, got)
+		}
+	})
+
+	t.Run("set new key and section", func(t *testing.T) {
+		doc := parse(t)
+		doc.Set("database", "user", " padded")
+		doc.Set("tls", "enabled", "true")
+
+		expected := `# App config
+[database]
+host = db   # primary
+  port=5432
+name = "my db"
+user = " padded"
+
+# Cache settings
+[cache]
+ttl = 60s
+
+[tls]
+enabled = true
+`
+		if got := doc.String(); got != expected {
+			t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+		}
+		if val, ok := doc.Get("database", "user"); !ok || val != " padded" {
+			t.Errorf("Expected ' padded', got %q", val)
+		}
+	})
+
+	t.Run("set rejects what can't be read back", func(t *testing.T) {
+		tests := []struct{ section, key, val string }{
+			{"database", "host", "x\n[admin]\nenabled = true"},
+			{"database", "host", `it's "x" #1`},
+			{"database", "a=b", "v"},
+			{"database", " padded", "v"},
+			{"data]base", "host", "v"},
+			{"", "host", "v"},
+		}
+		for _, tt := range tests {
+			doc := parse(t)
+			if err := doc.Set(tt.section, tt.key, tt.val); err == nil {
+				t.Errorf("Expected error for %q %q %q", tt.section, tt.key, tt.val)
+			}
+			if got := doc.String(); got != content {
+				t.Errorf("Expected document to be unchanged, got:\n%s", got)
+			}
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		doc := parse(t)
+		if !doc.Delete("database", "port") || doc.Delete("database", "missing") {
+			t.Error("Unexpected Delete result")
+		}
+		if !doc.DeleteSection("cache") || doc.DeleteSection("missing") {
+			t.Error("Unexpected DeleteSection result")
+		}
+
+		expected := `# App config
+[database]
+host = db   # primary
+name = "my db"
+
+`
+		if got := doc.String(); got != expected {
+			t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+		}
+	})
+
+	t.Run("clone is independent", func(t *testing.T) {
+		doc := parse(t)
+		clone := doc.Clone()
+		clone.Delete("database", "host")
+		if _, ok := doc.Get("database", "host"); !ok {
+			t.Error("Expected original to be unchanged")
+		}
+	})
+
+	t.Run("issues", func(t *testing.T) {
+		doc, issues, err := ParseIniDoc(strings.NewReader("orphan = 1\n[bad\n[s]\n= novalue\n"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(issues) != 3 {
+			t.Errorf("Expected 3 issues, got %v", issues)
+		}
+		if len(doc.AsMapOfSections()) != 1 {
+			t.Errorf("Unexpected map %v", doc.AsMapOfSections())
+		}
+	})
+
+	t.Run("read and write file", func(t *testing.T) {
+		filespec := filepath.Join(t.TempDir(), "app.ini")
+		if err := os.WriteFile(filespec, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+
+		doc, _, err := ReadIniDoc(filespec)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		doc.Set("cache", "ttl", "5m")
+		if err := doc.WriteFile(filespec); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		data, _ := os.ReadFile(filespec)
+		if !strings.Contains(string(data), "ttl = 5m\n") {
+			t.Errorf("Unexpected content %s", data)
+		}
+		if info, _ := os.Stat(filespec); info.Mode().Perm() != 0600 {
+			t.Errorf("Expected permissions to be kept, got %v", info.Mode())
+		}
+	})
+}
@@ -0,0 +1,228 @@
+package fileops
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-serr/serr"
+)
+
+// IniFlags tracks flags bound to ini keys so that command line values can beat file values
+type IniFlags struct {
+	fs       *flag.FlagSet
+	sections map[string]map[string]string // values read from file
+	sectKeys map[string]string            // flag name to "section::key"
+}
+
+// BindIniFlags registers a string flag named like section.key (see FlagName) on fs for every key
+// in sections, and for every key in schema if given, using the ini value as the flag default.
+// Flags which are already defined on fs are left alone
+func BindIniFlags(fs *flag.FlagSet, sections map[string]map[string]string, optSchema ...Schema) *IniFlags {
+	f := newIniFlags(fs, sections)
+
+	usages := make(map[string]string, 16)
+	for section, attrs := range sections {
+		for key := range attrs {
+			usages[section+sectKeySep+key] = "[" + section + "] " + key
+		}
+	}
+	if len(optSchema) > 0 {
+		for section, sectSchema := range optSchema[0].Sections {
+			for key, keySchema := range sectSchema.Keys {
+				usage := "[" + section + "] " + key
+				if keySchema.Type != "" {
+					usage += " (" + string(keySchema.Type) + ")"
+				}
+				usages[section+sectKeySep+key] = usage
+			}
+		}
+	}
+
+	for _, sectKey := range sortedKeys(usages) {
+		section, key, _ := strings.Cut(sectKey, sectKeySep)
+		def, ok := sections[section][key]
+		if !ok && len(optSchema) > 0 {
+			def = optSchema[0].Sections[section].Keys[key].Default
+		}
+
+		name := FlagName(section, key)
+		if fs.Lookup(name) != nil {
+			continue
+		}
+		fs.String(name, def, usages[sectKey])
+		f.sectKeys[name] = sectKey
+	}
+
+	return f
+}
+
+// BindStructFlags registers a flag on fs for every field of the struct pointed to by ptr that
+// has an `ini:"section::key"` tag, first setting the field from sections. A struct field tagged
+// `ini:"section"` binds its own fields tagged `ini:"key"`. An optional `usage` tag gives the help.
+// Supported field types are string, bool, int, int64, uint, uint64, float64 and time.Duration.
+// Issues are returned for ini values that don't parse as the field's type
+func BindStructFlags(fs *flag.FlagSet, ptr any, sections map[string]map[string]string) (
+	f *IniFlags, issues []serr.SErr, err error) {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return nil, nil, serr.New("BindStructFlags requires a pointer to a struct",
+			"type", fmt.Sprintf("%T", ptr))
+	}
+
+	f = newIniFlags(fs, sections)
+	issues, err = f.bindStruct(rv.Elem(), "")
+	return
+}
+
+// ExplicitlySet returns the "section::key" keys of flags that were set on the command line, sorted
+func (f *IniFlags) ExplicitlySet() (sectKeys []string) {
+	f.fs.Visit(func(fl *flag.Flag) {
+		if sectKey, ok := f.sectKeys[fl.Name]; ok {
+			sectKeys = append(sectKeys, sectKey)
+		}
+	})
+	sort.Strings(sectKeys)
+	return
+}
+
+// Values returns a copy of the ini values with the values of explicitly set flags applied
+func (f *IniFlags) Values() map[string]map[string]string {
+	values := copySections(f.sections)
+
+	f.fs.Visit(func(fl *flag.Flag) {
+		sectKey, ok := f.sectKeys[fl.Name]
+		if !ok {
+			return
+		}
+		section, key, _ := strings.Cut(sectKey, sectKeySep)
+		if values[section] == nil {
+			values[section] = make(map[string]string, 4)
+		}
+		values[section][key] = fl.Value.String()
+	})
+	return values
+}
+
+func newIniFlags(fs *flag.FlagSet, sections map[string]map[string]string) *IniFlags {
+	return &IniFlags{fs: fs, sections: sections, sectKeys: make(map[string]string, 16)}
+}
+
+// bindStruct binds the tagged fields of sv. section is set when sv is itself a tagged section
+func (f *IniFlags) bindStruct(sv reflect.Value, section string) (issues []serr.SErr, err error) {
+	st := sv.Type()
+
+	for i := 0; i < st.NumField(); i++ {
+		field := st.Field(i)
+		tag, ok := field.Tag.Lookup("ini")
+		if !ok || !field.IsExported() {
+			continue
+		}
+		fv := sv.Field(i)
+
+		if section == "" && !strings.Contains(tag, sectKeySep) {
+			if fv.Kind() != reflect.Struct {
+				return issues, serr.New("ini tag must be section::key", "field", field.Name, "tag", tag)
+			}
+			structIssues, err := f.bindStruct(fv, tag)
+			issues = append(issues, structIssues...)
+			if err != nil {
+				return issues, err
+			}
+			continue
+		}
+
+		sectKey := tag
+		if section != "" {
+			sectKey = section + sectKeySep + tag
+		}
+		sect, key, _ := strings.Cut(sectKey, sectKeySep)
+
+		if val, ok := f.sections[sect][key]; ok {
+			if err := setFieldFromString(fv, val); err != nil {
+				issues = append(issues, serr.NewSErr("Invalid value for field", "field", field.Name,
+					"section", sect, "key", key, "value", val, "error", err.Error()))
+			}
+		}
+
+		name := FlagName(sect, key)
+		if f.fs.Lookup(name) != nil {
+			return issues, serr.New("Flag already defined", "flag", name, "field", field.Name)
+		}
+		usage := field.Tag.Get("usage")
+		if usage == "" {
+			usage = "[" + sect + "] " + key
+		}
+
+		switch p := fv.Addr().Interface().(type) {
+		case *string:
+			f.fs.StringVar(p, name, *p, usage)
+		case *bool:
+			f.fs.BoolVar(p, name, *p, usage)
+		case *time.Duration:
+			f.fs.DurationVar(p, name, *p, usage)
+		case *int:
+			f.fs.IntVar(p, name, *p, usage)
+		case *int64:
+			f.fs.Int64Var(p, name, *p, usage)
+		case *uint:
+			f.fs.UintVar(p, name, *p, usage)
+		case *uint64:
+			f.fs.Uint64Var(p, name, *p, usage)
+		case *float64:
+			f.fs.Float64Var(p, name, *p, usage)
+		default:
+			return issues, serr.New("Unsupported field type", "field", field.Name, "type", fv.Type().String())
+		}
+		f.sectKeys[name] = sectKey
+	}
+
+	return
+}
+
+// setFieldFromString parses val into fv according to fv's type
+func setFieldFromString(fv reflect.Value, val string) error {
+	if fv.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(val)
+		if err == nil {
+			fv.SetInt(int64(d))
+		}
+		return err
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(val, 0, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(val, 0, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		fl, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(fl)
+	default:
+		return serr.New("Unsupported type", "type", fv.Type().String())
+	}
+	return nil
+}
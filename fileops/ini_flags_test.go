@@ -0,0 +1,103 @@
+package fileops
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBindIniFlags(t *testing.T) {
+	sections := map[string]map[string]string{
+		"database": {"host": "file-db", "port": "5432"},
+	}
+	schema := Schema{Sections: map[string]SectionSchema{
+		"database": {Keys: map[string]KeySchema{
+			"port":    {Type: TypeInt},
+			"sslmode": {Default: "disable"},
+		}},
+	}}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := BindIniFlags(fs, sections, schema)
+
+	if fl := fs.Lookup("database.port"); fl == nil || fl.DefValue != "5432" || fl.Usage != "[database] port (int)" {
+		t.Errorf("Unexpected flag %+v", fl)
+	}
+	if fl := fs.Lookup("database.sslmode"); fl == nil || fl.DefValue != "disable" {
+		t.Errorf("Unexpected flag %+v", fl)
+	}
+
+	if err := fs.Parse([]string{"-database.host", "cli-db", "-database.sslmode=require"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	expectedSet := []string{"database::host", "database::sslmode"}
+	if set := flags.ExplicitlySet(); !reflect.DeepEqual(set, expectedSet) {
+		t.Errorf("Expected %v, got %v", expectedSet, set)
+	}
+
+	expected := map[string]map[string]string{
+		"database": {"host": "cli-db", "port": "5432", "sslmode": "require"},
+	}
+	if values := flags.Values(); !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected %v, got %v", expected, values)
+	}
+	if sections["database"]["host"] != "file-db" {
+		t.Error("Expected file values to be left unchanged")
+	}
+}
+
+func TestBindStructFlags(t *testing.T) {
+	type Database struct {
+		Host    string        `ini:"host" usage:"database host"`
+		Port    int           `ini:"port"`
+		Timeout time.Duration `ini:"timeout"`
+	}
+	type Config struct {
+		Database Database `ini:"database"`
+		Debug    bool     `ini:"app::debug"`
+		Ratio    float64  `ini:"app::ratio"`
+		Ignored  string
+	}
+
+	sections := map[string]map[string]string{
+		"database": {"host": "file-db", "port": "5432", "timeout": "5s"},
+		"app":      {"debug": "true", "ratio": "not-a-float"},
+	}
+
+	var cfg Config
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flags, issues, err := BindStructFlags(fs, &cfg, sections)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(issues) != 1 {
+		t.Errorf("Expected 1 issue, got %v", issues)
+	}
+
+	if err := fs.Parse([]string{"-database.port=6543", "-app.debug=false"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	expected := Config{Database: Database{Host: "file-db", Port: 6543, Timeout: 5 * time.Second}}
+	if cfg != expected {
+		t.Errorf("Expected %+v, got %+v", expected, cfg)
+	}
+	if fl := fs.Lookup("database.host"); fl == nil || fl.Usage != "database host" {
+		t.Errorf("Unexpected flag %+v", fl)
+	}
+
+	expectedSet := []string{"app::debug", "database::port"}
+	if set := flags.ExplicitlySet(); !reflect.DeepEqual(set, expectedSet) {
+		t.Errorf("Expected %v, got %v", expectedSet, set)
+	}
+
+	t.Run("not a struct pointer", func(t *testing.T) {
+		if _, _, err := BindStructFlags(flag.NewFlagSet("test", flag.ContinueOnError), cfg, sections); err == nil {
+			t.Error("Expected error but got none")
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package fileops
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-serr/serr"
+)
+
+// QuoteStyle is how FormatIni quotes values
+type QuoteStyle int
+
+const (
+	QuoteAsNeeded QuoteStyle = iota // quote only values the readers would otherwise change
+	QuoteKeep                       // keep quotes as written, adding them where needed
+	QuoteAlways                     // quote every non-empty value, preferring double quotes
+)
+
+// FormatOptions controls FormatIni
+type FormatOptions struct {
+	Quotes        QuoteStyle
+	SortKeys      bool // sort keys within sections, with the comment lines directly above them
+	AlignComments bool // align inline comments within each section
+}
+
+// FormatIni canonicalizes an ini document: `key = value` spacing, quoting per opts.Quotes,
+// no indentation, a single blank line between sections and no runs of blank lines.
+// Comments are kept and values read back unchanged. Issues are returned for lines that
+// were left as written because they could not be formatted without changing their value
+func FormatIni(src []byte, opts FormatOptions) (formatted []byte, issues []serr.SErr, err error) {
+	doc, issues, err := ParseIniDoc(bytes.NewReader(src))
+	if err != nil {
+		return src, issues, err
+	}
+
+	// Split into blocks, each starting with the comment lines directly above a section header
+	var blocks [][]IniLine
+	start := 0
+	for i, line := range doc.Lines {
+		if line.Kind == LineSection {
+			if above := doc.commentsAbove(i); above > start {
+				blocks = append(blocks, doc.Lines[start:above])
+				start = above
+			}
+		}
+	}
+	blocks = append(blocks, doc.Lines[start:])
+
+	var out []string
+	for _, block := range blocks {
+		lines, blockIssues := formatIniBlock(block, opts)
+		issues = append(issues, blockIssues...)
+		if len(lines) == 0 {
+			continue
+		}
+		if len(out) > 0 {
+			out = append(out, "")
+		}
+		out = append(out, lines...)
+	}
+
+	if len(out) == 0 {
+		return []byte{}, issues, nil
+	}
+	return []byte(strings.Join(out, "\n") + "\n"), issues, nil
+}
+
+// IsIniFormatted reports whether src is unchanged by FormatIni with opts
+func IsIniFormatted(src []byte, opts FormatOptions) (bool, []serr.SErr, error) {
+	formatted, issues, err := FormatIni(src, opts)
+	if err != nil {
+		return false, issues, err
+	}
+	return bytes.Equal(src, formatted), issues, nil
+}
+
+// FormatIniFile formats filespec in place, returning whether it changed.
+// With checkOnly the file is left alone, so changed reports whether it is not yet formatted
+func FormatIniFile(filespec string, opts FormatOptions, checkOnly bool) (changed bool, issues []serr.SErr, err error) {
+	src, err := os.ReadFile(filespec)
+	if err != nil {
+		return false, issues, serr.Wrap(err, "Error reading: "+filespec)
+	}
+
+	formatted, issues, err := FormatIni(src, opts)
+	if err != nil {
+		return false, issues, serr.Wrap(err, "file", filespec)
+	}
+	changed = !bytes.Equal(src, formatted)
+	if !changed || checkOnly {
+		return
+	}
+
+	info, err := os.Stat(filespec)
+	if err != nil {
+		return changed, issues, serr.Wrap(err, "Error reading: "+filespec)
+	}
+	if err = os.WriteFile(filespec, formatted, info.Mode().Perm()); err != nil {
+		return changed, issues, serr.Wrap(err, "Error writing: "+filespec)
+	}
+	return
+}
+
+// formatIniBlock formats the lines of one section block, dropping leading, trailing and repeated blank lines
+func formatIniBlock(block []IniLine, opts FormatOptions) (out []string, issues []serr.SErr) {
+	type group struct {
+		lines []IniLine
+		key   string
+	}
+
+	// Group each key line with the comment lines directly above it so sorting keeps them together
+	var head, tail []IniLine
+	var groups []group
+	var pending []IniLine
+	headerSeen := false
+	for _, line := range block {
+		switch {
+		case !headerSeen:
+			head = append(head, line)
+			headerSeen = line.Kind == LineSection
+		case line.Kind == LineKeyValue:
+			groups = append(groups, group{lines: append(pending, line), key: line.Key})
+			pending = nil
+		case opts.SortKeys && line.Kind == LineBlank:
+			// blank lines between keys are dropped when sorting
+		default:
+			pending = append(pending, line)
+		}
+	}
+	tail = pending
+	if !headerSeen { // a preamble without a section header
+		head, tail = nil, head
+	}
+
+	if opts.SortKeys {
+		sort.SliceStable(groups, func(i, j int) bool { return groups[i].key < groups[j].key })
+	}
+
+	lines := append([]IniLine{}, head...)
+	for _, g := range groups {
+		lines = append(lines, g.lines...)
+	}
+	lines = append(lines, tail...)
+
+	// Format each line, measuring key lines for comment alignment
+	texts := make([]string, len(lines))
+	width := 0
+	for i, line := range lines {
+		switch line.Kind {
+		case LineSection:
+			texts[i] = "[" + line.Section + "]"
+		case LineKeyValue:
+			texts[i] = formatIniValueLine(line, opts.Quotes)
+			if parsed := parseIniLine(texts[i]+" "+line.Comment, line.Section); parsed.Value != line.Value {
+				texts[i] = strings.TrimSpace(line.Raw) // leave as written
+				issues = append(issues, serr.NewSErr("Value could not be formatted", "line", line.Raw,
+					"lineNbr", fmt.Sprintf("%d", line.LineNbr)))
+				continue
+			}
+			if line.Comment != "" {
+				width = max(width, len(texts[i]))
+			}
+		default:
+			texts[i] = strings.TrimSpace(line.Raw)
+		}
+	}
+
+	for i, line := range lines {
+		text := texts[i]
+		if line.Kind == LineKeyValue && line.Comment != "" && !strings.HasSuffix(text, line.Comment) {
+			if opts.AlignComments {
+				text += strings.Repeat(" ", width-len(text))
+			}
+			text += " " + line.Comment
+		}
+
+		if text == "" && (len(out) == 0 || out[len(out)-1] == "") {
+			continue
+		}
+		out = append(out, text)
+	}
+	for len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+	return
+}
+
+// formatIniValueLine returns `key = value` for line, without any comment
+func formatIniValueLine(line IniLine, style QuoteStyle) string {
+	if line.Value == "" {
+		return line.Key + " ="
+	}
+
+	switch style {
+	case QuoteKeep:
+		return line.Key + " = " + quoteIniValue(line.Value, line.Quote)
+	case QuoteAlways:
+		if strings.ContainsRune(line.Value, '"') {
+			return line.Key + " = " + quoteIniValue(line.Value, '\'')
+		}
+		return line.Key + " = " + quoteIniValue(line.Value, '"')
+	}
+	return line.Key + " = " + quoteIniValue(line.Value, 0)
+}
@@ -0,0 +1,151 @@
+package fileops
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFormatIni(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		opts     FormatOptions
+		expected string
+		issues   int
+	}{
+		{
+			name: "spacing, indentation and blank lines",
+			src: `
+
+# Database
+  [database]
+host=db
+  port   =   5432 # the port
+
+
+name = 'my db'
+[cache]
+ttl = 60s
+
+
+`,
+			expected: `# Database
+[database]
+host = db
+port = 5432 # the port
+
+name = my db
+
+[cache]
+ttl = 60s
+`,
+		},
+		{
+			name:     "quotes as needed",
+			src:      "[s]\na = \"plain\"\nb = ' padded'\nc = \"has # hash\"\nd = 'say \"hi\"'\n",
+			expected: "[s]\na = plain\nb = \" padded\"\nc = \"has # hash\"\nd = say \"hi\"\n",
+		},
+		{
+			name:     "quotes kept",
+			src:      "[s]\na = 'plain'\nb = plain\n",
+			opts:     FormatOptions{Quotes: QuoteKeep},
+			expected: "[s]\na = 'plain'\nb = plain\n",
+		},
+		{
+			name:     "quotes always",
+			src:      "[s]\na = plain\nb = 'say \"hi\"'\nc =\n",
+			opts:     FormatOptions{Quotes: QuoteAlways},
+			expected: "[s]\na = \"plain\"\nb = 'say \"hi\"'\nc =\n",
+		},
+		{
+			name: "sorted keys keep their comments",
+			src: `[s]
+zeta = 1
+
+# about alpha
+alpha = 2
+mid = 3 # inline
+# trailing comment
+`,
+			opts: FormatOptions{SortKeys: true},
+			expected: `[s]
+# about alpha
+alpha = 2
+mid = 3 # inline
+zeta = 1
+# trailing comment
+`,
+		},
+		{
+			name:     "aligned comments",
+			src:      "[s]\na = 1 # one\nlonger_key = 2 # two\nb = 3\n",
+			opts:     FormatOptions{AlignComments: true},
+			expected: "[s]\na = 1          # one\nlonger_key = 2 # two\nb = 3\n",
+		},
+		{
+			name:     "unformattable value left as written",
+			src:      "[s]\n  a = \"x\" junk\n",
+			expected: "[s]\na = \"x\" junk\n",
+			issues:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatted, issues, err := FormatIni([]byte(tt.src), tt.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(issues) != tt.issues {
+				t.Errorf("Expected %d issues, got %v", tt.issues, issues)
+			}
+			if string(formatted) != tt.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.expected, formatted)
+			}
+
+			// Formatting must not change values and must be idempotent
+			before, _, _ := ParseIniDoc(bytes.NewReader([]byte(tt.src)))
+			after, _, _ := ParseIniDoc(bytes.NewReader(formatted))
+			if !reflect.DeepEqual(before.AsMapOfSections(), after.AsMapOfSections()) {
+				t.Errorf("Values changed from %v to %v", before.AsMapOfSections(), after.AsMapOfSections())
+			}
+			if ok, _, _ := IsIniFormatted(formatted, tt.opts); !ok {
+				t.Error("Expected formatted output to be formatted")
+			}
+		})
+	}
+
+	t.Run("check mode", func(t *testing.T) {
+		if ok, _, _ := IsIniFormatted([]byte("[s]\na=1\n"), FormatOptions{}); ok {
+			t.Error("Expected unformatted")
+		}
+	})
+}
+
+func TestFormatIniFile(t *testing.T) {
+	filespec := filepath.Join(t.TempDir(), "app.ini")
+	if err := os.WriteFile(filespec, []byte("[s]\na=1\n"), 0600); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	changed, _, err := FormatIniFile(filespec, FormatOptions{}, true)
+	if err != nil || !changed {
+		t.Fatalf("Expected check to report a change, got %v, %v", changed, err)
+	}
+	if data, _ := os.ReadFile(filespec); string(data) != "[s]\na=1\n" {
+		t.Errorf("Expected check to leave the file alone, got %q", data)
+	}
+
+	if changed, _, err = FormatIniFile(filespec, FormatOptions{}, false); err != nil || !changed {
+		t.Fatalf("Expected a change, got %v, %v", changed, err)
+	}
+	if data, _ := os.ReadFile(filespec); string(data) != "[s]\na = 1\n" {
+		t.Errorf("Unexpected content %q", data)
+	}
+	if changed, _, _ = FormatIniFile(filespec, FormatOptions{}, true); changed {
+		t.Error("Expected no change once formatted")
+	}
+}
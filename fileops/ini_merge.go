@@ -0,0 +1,97 @@
+package fileops
+
+// MergeConflict is a key changed differently by ours and theirs. The merge keeps ours
+type MergeConflict struct {
+	Section  string
+	Key      string
+	Base     string
+	Ours     string
+	Theirs   string
+	InBase   bool
+	InOurs   bool
+	InTheirs bool
+	LineNbr  int // line number of the key in ours, if present
+}
+
+// Merge3 applies the changes from base to theirs onto ours, as when upgrading a package's
+// default config (theirs) over a user's edited copy (ours) of the previous default (base).
+// Keys the user hasn't changed take upstream changes, user edits are kept, and keys both
+// changed differently, or whose upstream value IniDoc.Set can't write, are reported as conflicts,
+// keeping ours. Comments and layout of ours are preserved, and sections new in theirs are copied
+// with their comments
+func Merge3(base, ours, theirs *IniDoc) (merged *IniDoc, conflicts []MergeConflict) {
+	merged = ours.Clone()
+	mBase, mOurs, mTheirs := base.AsMapOfSections(), ours.AsMapOfSections(), theirs.AsMapOfSections()
+	posOurs := ours.Positions()
+
+	// Sections new in theirs
+	for _, section := range theirs.Sections() {
+		if _, inBase := mBase[section]; inBase {
+			continue
+		}
+		if _, inOurs := mOurs[section]; inOurs {
+			continue
+		}
+		if n := len(merged.Lines); n > 0 && merged.Lines[n-1].Kind != LineBlank {
+			merged.Lines = append(merged.Lines, IniLine{Kind: LineBlank, Section: merged.Lines[n-1].Section})
+		}
+		for _, line := range theirs.SectionLines(section) {
+			line.LineNbr, line.Section = 0, section
+			merged.Lines = append(merged.Lines, line)
+		}
+		mOurs[section] = mTheirs[section] // treat as already merged
+	}
+
+	for _, section := range sortedKeys(unionKeys(mBase, mTheirs)) {
+		for _, key := range sortedKeys(unionKeys(mBase[section], mTheirs[section])) {
+			baseVal, inBase := mBase[section][key]
+			theirVal, inTheirs := mTheirs[section][key]
+			ourVal, inOurs := mOurs[section][key]
+
+			conflict := MergeConflict{Section: section, Key: key,
+				Base: baseVal, Ours: ourVal, Theirs: theirVal,
+				InBase: inBase, InOurs: inOurs, InTheirs: inTheirs,
+				LineNbr: posOurs.Of(section, key)}
+
+			switch {
+			case inBase == inTheirs && baseVal == theirVal:
+				// Unchanged upstream, so keep ours
+			case inOurs == inTheirs && ourVal == theirVal:
+				// Both made the same change
+			case inBase == inOurs && baseVal == ourVal:
+				// Unchanged by the user, so take theirs
+				if !inTheirs {
+					merged.Delete(section, key)
+				} else if err := merged.Set(section, key, theirVal); err != nil {
+					conflicts = append(conflicts, conflict) // theirs can't be written, so keep ours
+				}
+			default:
+				conflicts = append(conflicts, conflict)
+			}
+		}
+	}
+
+	// Sections removed upstream which the user hasn't changed
+	for section, baseAttrs := range mBase {
+		if _, inTheirs := mTheirs[section]; inTheirs {
+			continue
+		}
+		if ourAttrs, inOurs := mOurs[section]; inOurs && sameAttrs(baseAttrs, ourAttrs) {
+			merged.DeleteSection(section)
+		}
+	}
+
+	return
+}
+
+func sameAttrs(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, val := range a {
+		if other, ok := b[key]; !ok || other != val {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package fileops
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+	parse := func(t *testing.T, content string) *IniDoc {
+		doc, _, err := ParseIniDoc(strings.NewReader(content))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return doc
+	}
+
+	base := parse(t, `[server]
+port = 8080
+workers = 4
+timeout = 30s
+log = info
+
+[legacy]
+mode = old
+`)
+
+	ours := parse(t, `# My server
+[server]
+port = 9090 # changed by me
+workers = 4
+timeout = 60s
+log = info
+
+[legacy]
+mode = old
+`)
+
+	theirs := parse(t, `[server]
+port = 8080
+workers = 8
+timeout = 45s
+metrics = true
+
+# New in this release
+[tracing]
+enabled = false
+`)
+
+	merged, conflicts := Merge3(base, ours, theirs)
+
+	expected := `# My server
+[server]
+port = 9090 # changed by me
+workers = 8
+timeout = 60s
+metrics = true
+
+# New in this release
+[tracing]
+enabled = false
+`
+	if got := merged.String(); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+
+	expectedConflicts := []MergeConflict{
+		{Section: "server", Key: "timeout", Base: "30s", Ours: "60s", Theirs: "45s",
+			InBase: true, InOurs: true, InTheirs: true, LineNbr: 5},
+	}
+	if !reflect.DeepEqual(conflicts, expectedConflicts) {
+		t.Errorf("Expected %+v, got %+v", expectedConflicts, conflicts)
+	}
+
+	t.Run("user edited section removed upstream is kept", func(t *testing.T) {
+		ours := parse(t, "[legacy]\nmode = custom\n")
+		theirs := parse(t, "[server]\nport = 8080\n")
+		merged, conflicts := Merge3(parse(t, "[legacy]\nmode = old\n"), ours, theirs)
+
+		if got := merged.AsMapOfSections()["legacy"]["mode"]; got != "custom" {
+			t.Errorf("Expected custom, got %q", got)
+		}
+		if len(conflicts) != 1 || conflicts[0].InTheirs {
+			t.Errorf("Expected a removed/changed conflict, got %+v", conflicts)
+		}
+	})
+
+	t.Run("upstream value that can't be written is a conflict", func(t *testing.T) {
+		base := parse(t, "[server]\nmode = old\n")
+		theirs := parse(t, "[server]\nmode = new\n")
+		theirs.Lines[1].Value = "new\n[admin]\nenabled = true" // as built by a program, not read
+		merged, conflicts := Merge3(base, base, theirs)
+
+		if got, _ := merged.Get("server", "mode"); got != "old" {
+			t.Errorf("Expected ours to be kept, got %q", got)
+		}
+		if len(conflicts) != 1 || conflicts[0].Key != "mode" || conflicts[0].Theirs != theirs.Lines[1].Value {
+			t.Errorf("Expected a conflict for mode, got %+v", conflicts)
+		}
+	})
+
+	t.Run("inputs are unchanged", func(t *testing.T) {
+		if v, _ := ours.Get("server", "workers"); v != "4" {
+			t.Errorf("Expected ours to be unchanged, got %q", v)
+		}
+	})
+}
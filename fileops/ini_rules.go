@@ -0,0 +1,420 @@
+package fileops
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-rutil/rutil/cond"
+	"github.com/go-serr/serr"
+)
+
+// RuleCond is a condition over parsed ini data which remembers the "section::key" keys it refers to
+type RuleCond struct {
+	Pred cond.Pred[map[string]map[string]string]
+	Keys []string
+}
+
+// Rule is a named cross-key constraint over parsed ini data
+type Rule struct {
+	Name string
+	Expr string // the source expression, when built by ParseRule
+	Cond RuleCond
+}
+
+// Present is true when key ("section::key") has a value
+func Present(key string) RuleCond {
+	return RuleCond{
+		Pred: func(parsed map[string]map[string]string) bool {
+			_, ok := lookupKey(parsed, key)
+			return ok
+		},
+		Keys: []string{key},
+	}
+}
+
+// IsTrue is true when key has a value that strconv.ParseBool reads as true
+func IsTrue(key string) RuleCond {
+	return RuleCond{
+		Pred: func(parsed map[string]map[string]string) bool {
+			val, _ := lookupKey(parsed, key)
+			b, _ := strconv.ParseBool(val)
+			return b
+		},
+		Keys: []string{key},
+	}
+}
+
+// Compare is true when the value of keyA compares to the value of keyB as op,
+// one of `==`, `!=`, `<`, `<=`, `>`, `>=`. See CompareValue for how values compare
+func Compare(keyA, op, keyB string) RuleCond {
+	return RuleCond{
+		Pred: func(parsed map[string]map[string]string) bool {
+			a, okA := lookupKey(parsed, keyA)
+			b, okB := lookupKey(parsed, keyB)
+			return okA && okB && compareValues(a, op, b)
+		},
+		Keys: []string{keyA, keyB},
+	}
+}
+
+// CompareValue is true when the value of key compares to the literal val as op.
+// Values compare as numbers when both parse as floats or as durations, otherwise as strings.
+// A missing key makes any comparison false
+func CompareValue(key, op, val string) RuleCond {
+	return RuleCond{
+		Pred: func(parsed map[string]map[string]string) bool {
+			a, ok := lookupKey(parsed, key)
+			return ok && compareValues(a, op, val)
+		},
+		Keys: []string{key},
+	}
+}
+
+// AllOf is true when all of conds are true
+func AllOf(conds ...RuleCond) RuleCond {
+	preds, keys := splitConds(conds)
+	return RuleCond{Pred: cond.And(preds...), Keys: keys}
+}
+
+// AnyOf is true when any of conds is true
+func AnyOf(conds ...RuleCond) RuleCond {
+	preds, keys := splitConds(conds)
+	return RuleCond{Pred: cond.Or(preds...), Keys: keys}
+}
+
+// Negate is true when c is false
+func Negate(c RuleCond) RuleCond {
+	return RuleCond{Pred: cond.Not(c.Pred), Keys: c.Keys}
+}
+
+// Implies is true when ifCond is false or thenCond is true,
+// e.g. Implies(IsTrue("tls::enabled"), Present("tls::cert_file"))
+func Implies(ifCond, thenCond RuleCond) RuleCond {
+	keys := append(append([]string{}, ifCond.Keys...), thenCond.Keys...)
+	return RuleCond{Pred: cond.Implies(ifCond.Pred, thenCond.Pred), Keys: keys}
+}
+
+// CheckRules evaluates rules against parsed, returning an issue for every violated rule.
+// Issues list the keys involved and, if positions are given, the line number of each key found
+func CheckRules(parsed map[string]map[string]string, rules []Rule, optPositions ...Positions) (issues []serr.SErr) {
+	var positions Positions
+	if len(optPositions) > 0 {
+		positions = optPositions[0]
+	}
+
+	for _, rule := range rules {
+		if rule.Cond.Pred(parsed) {
+			continue
+		}
+
+		keys := uniqueStrings(rule.Cond.Keys)
+		fields := []string{"rule", rule.Name, "keys", strings.Join(keys, ", ")}
+		if rule.Expr != "" {
+			fields = append(fields, "expr", rule.Expr)
+		}
+
+		firstLineNbr := 0
+		for _, key := range keys {
+			if lineNbr := positions[key]; lineNbr > 0 {
+				fields = append(fields, key, fmt.Sprintf("%d", lineNbr))
+				if firstLineNbr == 0 || lineNbr < firstLineNbr {
+					firstLineNbr = lineNbr
+				}
+			}
+		}
+		issues = append(issues, positionedIssue("Rule violated", firstLineNbr, fields...))
+	}
+	return
+}
+
+// ParseRule builds a rule from an expression such as
+//
+//	tls::enabled => present(tls::cert_file)
+//	cache::ttl < cache::max_ttl
+//	db::mode == 'replica' && !present(db::primary) => false
+//
+// Expressions combine comparisons with `!`, `&&`, `||` and parentheses, and an optional
+// `=>` for "if ... then ...". Operands are `section::key` keys, numbers, quoted strings,
+// true and false. A key on its own is true when its value is a true bool
+func ParseRule(name, expr string) (rule Rule, err error) {
+	toks, err := tokenizeRule(expr)
+	if err != nil {
+		return rule, serr.Wrap(err, "rule", name, "expr", expr)
+	}
+
+	p := ruleParser{toks: toks}
+	c, err := p.parseRule()
+	if err == nil && p.pos < len(p.toks) {
+		err = serr.New("Unexpected token", "token", p.toks[p.pos])
+	}
+	if err != nil {
+		return rule, serr.Wrap(err, "rule", name, "expr", expr)
+	}
+
+	return Rule{Name: name, Expr: expr, Cond: c}, nil
+}
+
+// ruleParser is a recursive descent parser over rule tokens
+type ruleParser struct {
+	toks []string
+	pos  int
+}
+
+func (p *ruleParser) peek() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return ""
+}
+
+func (p *ruleParser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+// parseRule := or [ "=>" or ]
+func (p *ruleParser) parseRule() (RuleCond, error) {
+	c, err := p.parseOr()
+	if err != nil || p.peek() != "=>" {
+		return c, err
+	}
+	p.next()
+	then, err := p.parseOr()
+	return Implies(c, then), err
+}
+
+// parseOr := and { "||" and }
+func (p *ruleParser) parseOr() (RuleCond, error) {
+	c, err := p.parseAnd()
+	conds := []RuleCond{c}
+	for err == nil && p.peek() == "||" {
+		p.next()
+		c, err = p.parseAnd()
+		conds = append(conds, c)
+	}
+	if len(conds) == 1 {
+		return c, err
+	}
+	return AnyOf(conds...), err
+}
+
+// parseAnd := unary { "&&" unary }
+func (p *ruleParser) parseAnd() (RuleCond, error) {
+	c, err := p.parseUnary()
+	conds := []RuleCond{c}
+	for err == nil && p.peek() == "&&" {
+		p.next()
+		c, err = p.parseUnary()
+		conds = append(conds, c)
+	}
+	if len(conds) == 1 {
+		return c, err
+	}
+	return AllOf(conds...), err
+}
+
+// parseUnary := "!" unary | "(" or ")" | "present" "(" key ")" | operand [ op operand ]
+func (p *ruleParser) parseUnary() (c RuleCond, err error) {
+	switch tok := p.next(); {
+	case tok == "!":
+		c, err = p.parseUnary()
+		return Negate(c), err
+
+	case tok == "(":
+		c, err = p.parseOr()
+		if err == nil && p.next() != ")" {
+			err = serr.New("Expected ')'")
+		}
+		return c, err
+
+	case tok == "present" && p.peek() == "(":
+		p.next()
+		key := p.next()
+		if !isRuleKey(key) || p.next() != ")" {
+			return c, serr.New("Expected present(section::key)")
+		}
+		return Present(key), nil
+
+	case tok == "" || isRuleOp(tok):
+		return c, serr.New("Expected operand", "token", tok)
+
+	default:
+		if !isCompareOp(p.peek()) {
+			switch {
+			case isRuleKey(tok):
+				return IsTrue(tok), nil
+			case tok == "true" || tok == "false":
+				b := tok == "true"
+				return RuleCond{Pred: func(map[string]map[string]string) bool { return b }}, nil
+			}
+			return c, serr.New("Expected a key or comparison", "token", tok)
+		}
+
+		op := p.next()
+		rhs := p.next()
+		if rhs == "" || isRuleOp(rhs) {
+			return c, serr.New("Expected operand after "+op, "token", rhs)
+		}
+
+		switch {
+		case isRuleKey(tok) && isRuleKey(rhs):
+			return Compare(tok, op, rhs), nil
+		case isRuleKey(tok):
+			return CompareValue(tok, op, unquoteRuleLiteral(rhs)), nil
+		case isRuleKey(rhs):
+			return CompareValue(rhs, flipCompareOp(op), unquoteRuleLiteral(tok)), nil
+		}
+		return c, serr.New("Comparison needs at least one key", "token", tok)
+	}
+}
+
+// tokenizeRule splits a rule expression into operators, parentheses, quoted strings and words
+func tokenizeRule(expr string) (toks []string, err error) {
+	for i := 0; i < len(expr); {
+		ch := expr[i]
+		switch {
+		case ch == ' ' || ch == '\t':
+			i++
+		case ch == '(' || ch == ')':
+			toks = append(toks, string(ch))
+			i++
+		case ch == '\'' || ch == '"':
+			end := strings.IndexByte(expr[i+1:], ch)
+			if end == -1 {
+				return toks, serr.New("Unterminated string")
+			}
+			toks = append(toks, expr[i:i+end+2])
+			i += end + 2
+		case strings.ContainsRune("!=<>&|", rune(ch)):
+			if i+1 < len(expr) && isRuleOp(expr[i:i+2]) {
+				toks = append(toks, expr[i:i+2])
+				i += 2
+			} else if isRuleOp(string(ch)) {
+				toks = append(toks, string(ch))
+				i++
+			} else {
+				return toks, serr.New("Unknown operator", "char", string(ch))
+			}
+		default:
+			start := i
+			for i < len(expr) && !strings.ContainsRune(" \t()'\"!=<>&|", rune(expr[i])) {
+				i++
+			}
+			toks = append(toks, expr[start:i])
+		}
+	}
+	return
+}
+
+func isRuleOp(tok string) bool {
+	switch tok {
+	case "!", "&&", "||", "=>", ")":
+		return true
+	}
+	return isCompareOp(tok)
+}
+
+func isCompareOp(tok string) bool {
+	switch tok {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+func isRuleKey(tok string) bool {
+	bef, aft, fnd := strings.Cut(tok, sectKeySep)
+	return fnd && bef != "" && aft != ""
+}
+
+func flipCompareOp(op string) string {
+	switch op {
+	case "<":
+		return ">"
+	case "<=":
+		return ">="
+	case ">":
+		return "<"
+	case ">=":
+		return "<="
+	}
+	return op
+}
+
+func unquoteRuleLiteral(tok string) string {
+	if len(tok) >= 2 && (tok[0] == '\'' || tok[0] == '"') {
+		return tok[1 : len(tok)-1]
+	}
+	return tok
+}
+
+// compareValues compares a and b numerically if both are floats or durations, otherwise as strings
+func compareValues(a, op, b string) bool {
+	cmp := strings.Compare(a, b)
+
+	if fa, errA := strconv.ParseFloat(a, 64); errA == nil {
+		if fb, errB := strconv.ParseFloat(b, 64); errB == nil {
+			cmp = compareFloats(fa, fb)
+		}
+	} else if da, errA := time.ParseDuration(a); errA == nil {
+		if db, errB := time.ParseDuration(b); errB == nil {
+			cmp = compareFloats(float64(da), float64(db))
+		}
+	}
+
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// lookupKey returns the value of a "section::key" key in parsed
+func lookupKey(parsed map[string]map[string]string, sectKey string) (val string, ok bool) {
+	section, key, _ := strings.Cut(sectKey, sectKeySep)
+	val, ok = parsed[section][key]
+	return
+}
+
+func splitConds(conds []RuleCond) (preds []cond.Pred[map[string]map[string]string], keys []string) {
+	for _, c := range conds {
+		preds = append(preds, c.Pred)
+		keys = append(keys, c.Keys...)
+	}
+	return
+}
+
+// uniqueStrings returns strs without duplicates, keeping first occurrences in order
+func uniqueStrings(strs []string) (out []string) {
+	seen := make(map[string]bool, len(strs))
+	for _, s := range strs {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return
+}
@@ -0,0 +1,80 @@
+package fileops
+
+import (
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	parsed := map[string]map[string]string{
+		"tls":   {"enabled": "true"},
+		"cache": {"ttl": "90s", "max_ttl": "1m", "size": "10", "max_size": "100"},
+		"db":    {"mode": "replica"},
+	}
+
+	tests := []struct {
+		name     string
+		expr     string
+		cond     RuleCond // used when expr is empty
+		violated bool
+	}{
+		{name: "implies violated", expr: "tls::enabled => present(tls::cert_file)", violated: true},
+		{name: "implies vacuous", expr: "tls::disabled => present(tls::cert_file)"},
+		{name: "duration compare violated", expr: "cache::ttl < cache::max_ttl", violated: true},
+		{name: "numeric compare", expr: "cache::size < cache::max_size"},
+		{name: "literal compare", expr: "cache::size >= 10"},
+		{name: "literal on left", expr: "100 > cache::size"},
+		{name: "string equality", expr: `db::mode == 'replica' && !present(db::primary) => false`, violated: true},
+		{name: "or and parens", expr: `(db::mode == "primary" || db::mode == "replica") && tls::enabled`},
+		{name: "missing key compares false", expr: "db::port > 0", violated: true},
+		{name: "builder", cond: Implies(IsTrue("tls::enabled"), Present("tls::cert_file")), violated: true},
+		{name: "builder all of", cond: AllOf(Present("db::mode"), Negate(Present("db::primary")))},
+		{name: "builder any of", cond: AnyOf(Present("db::primary"), CompareValue("db::mode", "!=", "primary"))},
+		{name: "builder compare", cond: Compare("cache::size", "<=", "cache::max_size")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := Rule{Name: tt.name, Cond: tt.cond}
+			if tt.expr != "" {
+				var err error
+				rule, err = ParseRule(tt.name, tt.expr)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+
+			issues := CheckRules(parsed, []Rule{rule})
+			if violated := len(issues) > 0; violated != tt.violated {
+				t.Errorf("Expected violated=%v, got issues %v", tt.violated, issues)
+			}
+		})
+	}
+
+	t.Run("issue positions", func(t *testing.T) {
+		rule, err := ParseRule("tls", "tls::enabled => present(tls::cert_file)")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		issues := CheckRules(parsed, []Rule{rule}, Positions{"tls::enabled": 4})
+		if len(issues) != 1 {
+			t.Fatalf("Expected 1 issue, got %v", issues)
+		}
+		fields := issues[0].FieldsMap()
+		if fields["rule"] != "tls" || fields["keys"] != "tls::enabled, tls::cert_file" ||
+			fields["lineNbr"] != "4" || fields["tls::enabled"] != "4" {
+			t.Errorf("Unexpected fields: %v", fields)
+		}
+	})
+
+	t.Run("parse errors", func(t *testing.T) {
+		for _, expr := range []string{
+			"", "cache::ttl <", "cache::ttl = 1", "(tls::enabled", "present(ttl)",
+			"1 < 2", "tls::enabled tls::enabled", "'unterminated", "ttl",
+		} {
+			if _, err := ParseRule("bad", expr); err == nil {
+				t.Errorf("Expected error for %q", expr)
+			}
+		}
+	})
+}
@@ -0,0 +1,415 @@
+package fileops
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-serr/serr"
+)
+
+// ValueType names the type a schema expects an ini value to parse as
+type ValueType string
+
+const (
+	TypeString   ValueType = "string"
+	TypeInt      ValueType = "int"
+	TypeFloat    ValueType = "float"
+	TypeBool     ValueType = "bool"     // as accepted by strconv.ParseBool
+	TypeDuration ValueType = "duration" // as accepted by time.ParseDuration
+)
+
+// KeySchema describes the allowed values of a single key.
+// Min and Max apply to int, float and duration (in seconds) values
+type KeySchema struct {
+	Type     ValueType `json:"type,omitempty"`
+	Required bool      `json:"required,omitempty"`
+	Enum     []string  `json:"enum,omitempty"`
+	Min      *float64  `json:"min,omitempty"`
+	Max      *float64  `json:"max,omitempty"`
+	Pattern  string    `json:"pattern,omitempty"` // regular expression the whole value must match
+	Default  string    `json:"default,omitempty"` // a key with a default is never reported missing
+}
+
+// SectionSchema describes the keys allowed in a section
+type SectionSchema struct {
+	Required         bool                 `json:"required,omitempty"`
+	AllowUnknownKeys bool                 `json:"allow_unknown_keys,omitempty"`
+	Keys             map[string]KeySchema `json:"keys,omitempty"`
+}
+
+// Schema describes the sections allowed in an ini file
+type Schema struct {
+	AllowUnknownSections bool                     `json:"allow_unknown_sections,omitempty"`
+	Sections             map[string]SectionSchema `json:"sections,omitempty"`
+}
+
+// Validate checks parsed ini data (as from ReadIniAsMapOfSections) against schema,
+// returning an issue for every unknown section or key, missing section or key, and bad value.
+// Invalid patterns in schema are reported once per key, and values aren't checked against them.
+// If positions are given (as from ReadIniWithPositions) issues include the lineNbr
+func Validate(parsed map[string]map[string]string, schema Schema, optPositions ...Positions) (issues []serr.SErr) {
+	var positions Positions
+	if len(optPositions) > 0 {
+		positions = optPositions[0]
+	}
+
+	for _, section := range sortedKeys(schema.Sections) {
+		for _, key := range sortedKeys(schema.Sections[section].Keys) {
+			pattern := schema.Sections[section].Keys[key].Pattern
+			if _, err := schemaPattern(pattern); err != nil {
+				issues = append(issues, serr.NewSErr("Invalid pattern in schema",
+					"section", section, "key", key, "pattern", pattern))
+			}
+		}
+	}
+
+	for _, section := range sortedKeys(parsed) {
+		sectSchema, ok := schema.Sections[section]
+		if !ok {
+			if !schema.AllowUnknownSections {
+				issues = append(issues, positionedIssue("Unknown section", positions.OfSection(section),
+					withSuggestion([]string{"section", section}, section, sortedKeys(schema.Sections))...))
+			}
+			continue
+		}
+
+		for _, key := range sortedKeys(parsed[section]) {
+			val := parsed[section][key]
+			lineNbr := positions.Of(section, key)
+
+			keySchema, ok := sectSchema.Keys[key]
+			if !ok {
+				if !sectSchema.AllowUnknownKeys {
+					issues = append(issues, positionedIssue("Unknown key", lineNbr,
+						withSuggestion([]string{"section", section, "key", key}, key, sortedKeys(sectSchema.Keys))...))
+				}
+				continue
+			}
+
+			if msg := checkValue(val, keySchema); msg != "" {
+				issues = append(issues, positionedIssue(msg, lineNbr, "section", section, "key", key, "value", val))
+			}
+		}
+	}
+
+	// Missing sections and keys
+	for _, section := range sortedKeys(schema.Sections) {
+		sectSchema := schema.Sections[section]
+		attrs, present := parsed[section]
+		if !present {
+			if sectSchema.Required || hasRequiredKeys(sectSchema) {
+				issues = append(issues, serr.NewSErr("Missing required section", "section", section))
+			}
+			continue
+		}
+
+		for _, key := range sortedKeys(sectSchema.Keys) {
+			keySchema := sectSchema.Keys[key]
+			if _, ok := attrs[key]; !ok && keySchema.Required && keySchema.Default == "" {
+				issues = append(issues, positionedIssue("Missing required key", positions.OfSection(section),
+					"section", section, "key", key))
+			}
+		}
+	}
+
+	return
+}
+
+// ApplyDefaults fills in schema defaults for keys missing from parsed, adding sections as needed
+func ApplyDefaults(parsed map[string]map[string]string, schema Schema) {
+	for section, sectSchema := range schema.Sections {
+		for key, keySchema := range sectSchema.Keys {
+			if keySchema.Default == "" {
+				continue
+			}
+			if parsed[section] == nil {
+				parsed[section] = make(map[string]string, len(sectSchema.Keys))
+			}
+			if _, ok := parsed[section][key]; !ok {
+				parsed[section][key] = keySchema.Default
+			}
+		}
+	}
+}
+
+// ReadSchemaJSON reads a schema from a JSON document. An invalid pattern is an error
+func ReadSchemaJSON(filespec string) (schema Schema, err error) {
+	data, err := os.ReadFile(filespec)
+	if err != nil {
+		return schema, serr.Wrap(err, "Error reading: "+filespec)
+	}
+
+	if err = json.Unmarshal(data, &schema); err != nil {
+		return schema, serr.Wrap(err, "Error parsing schema: "+filespec)
+	}
+
+	for _, section := range sortedKeys(schema.Sections) {
+		for _, key := range sortedKeys(schema.Sections[section].Keys) {
+			pattern := schema.Sections[section].Keys[key].Pattern
+			if _, err := schemaPattern(pattern); err != nil {
+				return schema, serr.New("Invalid pattern in schema", "file", filespec,
+					"section", section, "key", key, "pattern", pattern)
+			}
+		}
+	}
+	return
+}
+
+// ReadSchemaIni reads a schema from a `.schema.ini` file in which each key's value is a
+// semicolon separated spec, e.g.
+//
+//	[database]
+//	port = "int; required; min=1; max=65535; default=5432"
+//	sslmode = "string; enum=disable|require|verify-full"
+//
+// A `_section` key holds section options (`required`, `allow_unknown_keys`)
+// and an `allow_unknown_sections` key in a `[_schema]` section holds schema options
+func ReadSchemaIni(filespec string) (schema Schema, issues []serr.SErr, err error) {
+	attrsBySection, positions, issues, err := ReadIniWithPositions(filespec)
+	if err != nil {
+		return schema, issues, err
+	}
+
+	schema.Sections = make(map[string]SectionSchema, len(attrsBySection))
+
+	for section, attrs := range attrsBySection {
+		if section == "_schema" {
+			schema.AllowUnknownSections, _ = strconv.ParseBool(attrs["allow_unknown_sections"])
+			continue
+		}
+
+		sectSchema := SectionSchema{Keys: make(map[string]KeySchema, len(attrs))}
+		for key, spec := range attrs {
+			if key == "_section" {
+				for _, opt := range strings.Split(spec, ";") {
+					switch strings.TrimSpace(opt) {
+					case "required":
+						sectSchema.Required = true
+					case "allow_unknown_keys":
+						sectSchema.AllowUnknownKeys = true
+					}
+				}
+				continue
+			}
+
+			keySchema, msg := parseKeySpec(spec)
+			if msg != "" {
+				issues = append(issues, positionedIssue(msg, positions.Of(section, key),
+					"section", section, "key", key, "spec", spec))
+				continue
+			}
+			sectSchema.Keys[key] = keySchema
+		}
+		schema.Sections[section] = sectSchema
+	}
+
+	return
+}
+
+// parseKeySpec parses a spec such as "int; required; min=1" returning a message if it is invalid
+func parseKeySpec(spec string) (keySchema KeySchema, msg string) {
+	keySchema.Type = TypeString
+
+	for _, tok := range strings.Split(spec, ";") {
+		tok = strings.TrimSpace(tok)
+		if tok == "" {
+			continue
+		}
+
+		name, arg, hasArg := strings.Cut(tok, "=")
+		name = strings.TrimSpace(name)
+		arg = strings.TrimSpace(arg)
+
+		switch {
+		case !hasArg && name == "required":
+			keySchema.Required = true
+		case !hasArg && isValueType(ValueType(name)):
+			keySchema.Type = ValueType(name)
+		case hasArg && name == "type" && isValueType(ValueType(arg)):
+			keySchema.Type = ValueType(arg)
+		case hasArg && (name == "min" || name == "max"):
+			f, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return keySchema, "Invalid " + name + " in schema"
+			}
+			if name == "min" {
+				keySchema.Min = &f
+			} else {
+				keySchema.Max = &f
+			}
+		case hasArg && name == "enum":
+			keySchema.Enum = strings.Split(arg, "|")
+		case hasArg && name == "pattern":
+			if _, err := schemaPattern(arg); err != nil {
+				return keySchema, "Invalid pattern in schema"
+			}
+			keySchema.Pattern = arg
+		case hasArg && name == "default":
+			keySchema.Default = arg
+		default:
+			return keySchema, "Unknown schema option: " + tok
+		}
+	}
+	return
+}
+
+// checkValue returns a message describing why val does not satisfy keySchema, or "" if it does
+func checkValue(val string, keySchema KeySchema) string {
+	var num float64
+	isNum := false
+
+	switch keySchema.Type {
+	case TypeInt:
+		i, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return "Value is not an int"
+		}
+		num, isNum = float64(i), true
+	case TypeFloat:
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return "Value is not a float"
+		}
+		num, isNum = f, true
+	case TypeBool:
+		if _, err := strconv.ParseBool(val); err != nil {
+			return "Value is not a bool"
+		}
+	case TypeDuration:
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return "Value is not a duration"
+		}
+		num, isNum = d.Seconds(), true
+	case TypeString, "":
+	default:
+		return "Unknown type in schema: " + string(keySchema.Type)
+	}
+
+	if isNum && keySchema.Min != nil && num < *keySchema.Min {
+		return fmt.Sprintf("Value is less than minimum of %v", *keySchema.Min)
+	}
+	if isNum && keySchema.Max != nil && num > *keySchema.Max {
+		return fmt.Sprintf("Value is greater than maximum of %v", *keySchema.Max)
+	}
+
+	if len(keySchema.Enum) > 0 {
+		found := false
+		for _, e := range keySchema.Enum {
+			if val == e {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "Value is not one of: " + strings.Join(keySchema.Enum, ", ")
+		}
+	}
+
+	// An invalid pattern is reported by Validate once, rather than for each value
+	if re, err := schemaPattern(keySchema.Pattern); err == nil && re != nil && !re.MatchString(val) {
+		return "Value does not match pattern: " + keySchema.Pattern
+	}
+
+	return ""
+}
+
+// schemaPatterns caches the regular expressions compiled by schemaPattern, by pattern
+var schemaPatterns sync.Map
+
+// schemaPattern returns pattern compiled to match whole values, or nil if pattern is empty.
+// Each pattern is compiled once
+func schemaPattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	if re, ok := schemaPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, err
+	}
+	schemaPatterns.Store(pattern, re)
+	return re, nil
+}
+
+func isValueType(t ValueType) bool {
+	switch t {
+	case TypeString, TypeInt, TypeFloat, TypeBool, TypeDuration:
+		return true
+	}
+	return false
+}
+
+func hasRequiredKeys(sectSchema SectionSchema) bool {
+	for _, keySchema := range sectSchema.Keys {
+		if keySchema.Required && keySchema.Default == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// positionedIssue builds an issue, adding the lineNbr field when it is known
+func positionedIssue(msg string, lineNbr int, fields ...string) serr.SErr {
+	if lineNbr > 0 {
+		fields = append(fields, "lineNbr", fmt.Sprintf("%d", lineNbr))
+	}
+	return serr.NewSErr(msg, fields...)
+}
+
+// withSuggestion appends a "suggestion" field when name looks like a typo of one of candidates
+func withSuggestion(fields []string, name string, candidates []string) []string {
+	best, bestDist := "", 3 // only suggest for up to 2 edits
+	for _, c := range candidates {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(c)); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	if best != "" {
+		fields = append(fields, "suggestion", best)
+	}
+	return fields
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// sortedKeys returns the keys of a string keyed map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
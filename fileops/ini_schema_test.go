@@ -0,0 +1,252 @@
+package fileops
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	one, maxPort := 1.0, 65535.0
+	schema := Schema{
+		Sections: map[string]SectionSchema{
+			"database": {
+				Keys: map[string]KeySchema{
+					"host":    {Type: TypeString, Required: true},
+					"port":    {Type: TypeInt, Min: &one, Max: &maxPort, Default: "5432"},
+					"sslmode": {Enum: []string{"disable", "require"}},
+					"user":    {Pattern: `[a-z]+`},
+					"timeout": {Type: TypeDuration, Max: &maxPort},
+					"debug":   {Type: TypeBool},
+				},
+			},
+			"cache": {AllowUnknownKeys: true},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		parsed       map[string]map[string]string
+		positions    Positions
+		expectedMsgs []string
+		expectedLine string // lineNbr of the first issue
+		suggestion   string // suggestion of the first issue
+	}{
+		{
+			name: "valid",
+			parsed: map[string]map[string]string{
+				"database": {"host": "db", "port": "5432", "sslmode": "require", "user": "app",
+					"timeout": "5s", "debug": "true"},
+				"cache": {"anything": "goes"},
+			},
+		},
+		{
+			name: "typo in section name",
+			parsed: map[string]map[string]string{
+				"database": {"host": "db"},
+				"databse":  {"host": "db"},
+			},
+			positions:    Positions{"databse": 7},
+			expectedMsgs: []string{"Unknown section"},
+			expectedLine: "7",
+			suggestion:   "database",
+		},
+		{
+			name:         "typo in key name",
+			parsed:       map[string]map[string]string{"database": {"host": "db", "prot": "1"}},
+			positions:    Positions{"database::prot": 3},
+			expectedMsgs: []string{"Unknown key"},
+			expectedLine: "3",
+			suggestion:   "port",
+		},
+		{
+			name: "bad values",
+			parsed: map[string]map[string]string{
+				"database": {"host": "db", "port": "70000", "sslmode": "nope", "user": "App1",
+					"timeout": "soon", "debug": "maybe"},
+			},
+			expectedMsgs: []string{
+				"Value is not a bool",
+				"Value is greater than maximum of 65535",
+				"Value is not one of: disable, require",
+				"Value is not a duration",
+				"Value does not match pattern: [a-z]+",
+			},
+		},
+		{
+			name:         "missing required key",
+			parsed:       map[string]map[string]string{"database": {"port": "1"}},
+			positions:    Positions{"database": 1},
+			expectedMsgs: []string{"Missing required key"},
+			expectedLine: "1",
+		},
+		{
+			name:         "missing section with required keys",
+			parsed:       map[string]map[string]string{},
+			expectedMsgs: []string{"Missing required section"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := Validate(tt.parsed, schema, tt.positions)
+
+			var msgs []string
+			for _, issue := range issues {
+				msgs = append(msgs, issue.Error())
+			}
+			if !reflect.DeepEqual(msgs, tt.expectedMsgs) {
+				t.Fatalf("Expected issues %q, got %q", tt.expectedMsgs, msgs)
+			}
+			if len(issues) == 0 {
+				return
+			}
+
+			fields := issues[0].FieldsMap()
+			if tt.expectedLine != "" && fields["lineNbr"] != tt.expectedLine {
+				t.Errorf("Expected lineNbr %q, got %q", tt.expectedLine, fields["lineNbr"])
+			}
+			if fields["suggestion"] != tt.suggestion {
+				t.Errorf("Expected suggestion %q, got %q", tt.suggestion, fields["suggestion"])
+			}
+		})
+	}
+
+	t.Run("unknown sections allowed", func(t *testing.T) {
+		issues := Validate(map[string]map[string]string{"other": {"a": "b"}},
+			Schema{AllowUnknownSections: true})
+		if len(issues) != 0 {
+			t.Errorf("Expected no issues, got %v", issues)
+		}
+	})
+
+	t.Run("invalid pattern is a schema issue", func(t *testing.T) {
+		bad := Schema{Sections: map[string]SectionSchema{
+			"database": {Keys: map[string]KeySchema{"user": {Pattern: `[a-`}, "role": {Pattern: `[a-`}}},
+		}}
+		issues := Validate(map[string]map[string]string{"database": {"user": "app"}}, bad)
+		if len(issues) != 2 {
+			t.Fatalf("Expected an issue per key, got %v", issues)
+		}
+		for _, issue := range issues {
+			if fields := issue.FieldsMap(); issue.Error() != "Invalid pattern in schema" || fields["value"] != "" {
+				t.Errorf("Expected a schema issue without a value, got %v %v", issue, fields)
+			}
+		}
+	})
+}
+
+func TestApplyDefaults(t *testing.T) {
+	schema := Schema{Sections: map[string]SectionSchema{
+		"database": {Keys: map[string]KeySchema{
+			"host": {Default: "localhost"},
+			"port": {Default: "5432"},
+			"user": {},
+		}},
+		"cache": {Keys: map[string]KeySchema{"ttl": {Default: "60s"}}},
+	}}
+
+	parsed := map[string]map[string]string{"database": {"host": "db"}}
+	ApplyDefaults(parsed, schema)
+
+	expected := map[string]map[string]string{
+		"database": {"host": "db", "port": "5432"},
+		"cache":    {"ttl": "60s"},
+	}
+	if !reflect.DeepEqual(parsed, expected) {
+		t.Errorf("Expected %v, got %v", expected, parsed)
+	}
+}
+
+func TestReadSchema(t *testing.T) {
+	one, maxPort := 1.0, 65535.0
+	expected := Schema{
+		AllowUnknownSections: true,
+		Sections: map[string]SectionSchema{
+			"database": {
+				Required: true,
+				Keys: map[string]KeySchema{
+					"host":    {Type: TypeString, Required: true},
+					"port":    {Type: TypeInt, Min: &one, Max: &maxPort, Default: "5432"},
+					"sslmode": {Type: TypeString, Enum: []string{"disable", "require"}},
+				},
+			},
+		},
+	}
+
+	t.Run("ini", func(t *testing.T) {
+		filespec := filepath.Join(t.TempDir(), "app.schema.ini")
+		content := `[_schema]
+allow_unknown_sections = true
+
+[database]
+_section = required
+host = "string; required"
+port = "int; min=1; max=65535; default=5432"
+sslmode = "enum=disable|require"
+`
+		if err := os.WriteFile(filespec, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+
+		schema, issues, err := ReadSchemaIni(filespec)
+		if err != nil || len(issues) != 0 {
+			t.Fatalf("Unexpected err: %v, issues: %v", err, issues)
+		}
+		if !reflect.DeepEqual(schema, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, schema)
+		}
+	})
+
+	t.Run("ini with bad spec", func(t *testing.T) {
+		filespec := filepath.Join(t.TempDir(), "app.schema.ini")
+		content := "[database]\nport = \"int; min=low\"\nhost = \"strin\"\nuser = \"pattern=[a-\"\n"
+		if err := os.WriteFile(filespec, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+
+		_, issues, err := ReadSchemaIni(filespec)
+		if err != nil {
+			t.Fatalf("Unexpected err: %v", err)
+		}
+		if len(issues) != 3 {
+			t.Errorf("Expected 3 issues, got %v", issues)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		filespec := filepath.Join(t.TempDir(), "app.schema.json")
+		content := `{
+  "allow_unknown_sections": true,
+  "sections": {
+    "database": {
+      "required": true,
+      "keys": {
+        "host": {"type": "string", "required": true},
+        "port": {"type": "int", "min": 1, "max": 65535, "default": "5432"},
+        "sslmode": {"type": "string", "enum": ["disable", "require"]}
+      }
+    }
+  }
+}`
+		if err := os.WriteFile(filespec, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+
+		schema, err := ReadSchemaJSON(filespec)
+		if err != nil {
+			t.Fatalf("Unexpected err: %v", err)
+		}
+		if !reflect.DeepEqual(schema, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, schema)
+		}
+
+		if err := os.WriteFile(filespec, []byte(`{"sections": {"db": {"keys": {"user": {"pattern": "[a-"}}}}}`), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		if _, err := ReadSchemaJSON(filespec); err == nil {
+			t.Error("Expected an error for an invalid pattern")
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package fileops
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-serr/serr"
+)
+
+// IssueRecord is a parse, validation or lint issue flattened for rendering
+type IssueRecord struct {
+	File     string            `json:"file,omitempty"`
+	Line     int               `json:"line,omitempty"`
+	Col      int               `json:"col,omitempty"`
+	Severity string            `json:"severity,omitempty"`
+	Rule     string            `json:"rule,omitempty"`
+	Message  string            `json:"message"`
+	Fields   map[string]string `json:"fields,omitempty"` // any other fields of the issue
+}
+
+// IssueRecords flattens issues using their "file", "lineNbr", "col", "severity" and "rule" fields.
+// filespec is used for issues without a file. The caller location fields serr adds are dropped
+func IssueRecords(filespec string, issues []serr.SErr) (records []IssueRecord) {
+	records = make([]IssueRecord, 0, len(issues))
+	for _, issue := range issues {
+		rec := IssueRecord{File: filespec, Message: issue.Error()}
+		for key, val := range issue.FieldsMap() {
+			switch key {
+			case "file":
+				rec.File = val
+			case "lineNbr":
+				rec.Line, _ = strconv.Atoi(val)
+			case "col":
+				rec.Col, _ = strconv.Atoi(val)
+			case "severity":
+				rec.Severity = val
+			case "rule":
+				rec.Rule = val
+			case "location", "function":
+			default:
+				if rec.Fields == nil {
+					rec.Fields = make(map[string]string, 4)
+				}
+				rec.Fields[key] = val
+			}
+		}
+		records = append(records, rec)
+	}
+	return
+}
+
+// WriteIssuesText writes issues compiler style, one per line, e.g.
+//
+//	app.ini:12:5: warn: Value looks like a secret [secret-value]
+//
+// The line, column and severity are left out when unknown
+func WriteIssuesText(w io.Writer, filespec string, issues []serr.SErr) error {
+	for _, rec := range IssueRecords(filespec, issues) {
+		var sb strings.Builder
+		sb.WriteString(rec.File)
+		if rec.Line > 0 {
+			sb.WriteString(":" + strconv.Itoa(rec.Line))
+			if rec.Col > 0 {
+				sb.WriteString(":" + strconv.Itoa(rec.Col))
+			}
+		}
+		sb.WriteString(": ")
+		if rec.Severity != "" {
+			sb.WriteString(rec.Severity + ": ")
+		}
+		sb.WriteString(rec.Message)
+		if rec.Rule != "" {
+			sb.WriteString(" [" + rec.Rule + "]")
+		}
+
+		if _, err := fmt.Fprintln(w, sb.String()); err != nil {
+			return serr.Wrap(err, "Error writing issues")
+		}
+	}
+	return nil
+}
+
+// IssuesJSON renders issues as an indented JSON array of IssueRecord, in the order given
+func IssuesJSON(filespec string, issues []serr.SErr) ([]byte, error) {
+	data, err := json.MarshalIndent(IssueRecords(filespec, issues), "", "  ")
+	if err != nil {
+		return nil, serr.Wrap(err, "Error rendering issues as JSON")
+	}
+	return data, nil
+}
+
+// IssuesSARIF renders issues as a SARIF 2.1.0 log with a single run of the tool toolName,
+// for code scanning dashboards. File paths are made relative to the current directory when possible
+func IssuesSARIF(toolName, filespec string, issues []serr.SErr) ([]byte, error) {
+	records := IssueRecords(filespec, issues)
+
+	ruleIDs := make(map[string]bool, 8)
+	results := make([]sarifResult, 0, len(records))
+	for _, rec := range records {
+		result := sarifResult{RuleID: rec.Rule, Level: sarifLevel(rec.Severity), Message: sarifMessage{Text: rec.Message}}
+		if rec.Rule != "" {
+			ruleIDs[rec.Rule] = true
+		}
+
+		if rec.File != "" {
+			loc := sarifLocation{}
+			loc.PhysicalLocation.ArtifactLocation.URI = sarifURI(rec.File)
+			if rec.Line > 0 {
+				loc.PhysicalLocation.Region = &sarifRegion{StartLine: rec.Line, StartColumn: rec.Col}
+			}
+			result.Locations = []sarifLocation{loc}
+		}
+		results = append(results, result)
+	}
+
+	rules := make([]sarifRule, 0, len(ruleIDs))
+	for _, id := range sortedKeys(ruleIDs) {
+		rules = append(rules, sarifRule{ID: id})
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: sarifDriver{Name: toolName, Rules: rules}}, Results: results}},
+	}
+
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return nil, serr.Wrap(err, "Error rendering issues as SARIF")
+	}
+	return data, nil
+}
+
+// sarifLevel maps serr severities to SARIF result levels
+func sarifLevel(severity string) string {
+	switch severity {
+	case serr.Severity.Error:
+		return "error"
+	case serr.Severity.Info, serr.Severity.Success:
+		return "note"
+	}
+	return "warning"
+}
+
+// sarifURI returns filespec as a relative, forward slashed URI where possible
+func sarifURI(filespec string) string {
+	if filepath.IsAbs(filespec) {
+		if wd, err := filepath.Abs("."); err == nil {
+			if rel, err := filepath.Rel(wd, filespec); err == nil && !strings.HasPrefix(rel, "..") {
+				filespec = rel
+			}
+		}
+	}
+	return filepath.ToSlash(filespec)
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId,omitempty"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region *sarifRegion `json:"region,omitempty"`
+	} `json:"physicalLocation"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
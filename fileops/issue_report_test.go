@@ -0,0 +1,118 @@
+package fileops
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/go-serr/serr"
+)
+
+func TestIssueReports(t *testing.T) {
+	issues := []serr.SErr{
+		serr.NewSErr("Value looks like a secret", "rule", RuleSecretValue, "severity", serr.Severity.Warn,
+			"lineNbr", "12", "col", "5", "key", "password"),
+		serr.NewSErr("Missing required section", "section", "database"),
+		serr.NewSErr("Key is empty", "rule", RuleSyntax, "severity", serr.Severity.Error,
+			"lineNbr", "3", "file", "other.ini"),
+	}
+
+	t.Run("records", func(t *testing.T) {
+		expected := []IssueRecord{
+			{File: "app.ini", Line: 12, Col: 5, Severity: "warn", Rule: RuleSecretValue,
+				Message: "Value looks like a secret", Fields: map[string]string{"key": "password"}},
+			{File: "app.ini", Message: "Missing required section", Fields: map[string]string{"section": "database"}},
+			{File: "other.ini", Line: 3, Severity: "error", Rule: RuleSyntax, Message: "Key is empty"},
+		}
+		if got := IssueRecords("app.ini", issues); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected %+v\ngot %+v", expected, got)
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteIssuesText(&buf, "app.ini", issues); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := `app.ini:12:5: warn: Value looks like a secret [secret-value]
+app.ini: Missing required section
+other.ini:3: error: Key is empty [syntax]
+`
+		if buf.String() != expected {
+			t.Errorf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		data, err := IssuesJSON("app.ini", issues)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var records []IssueRecord
+		if err := json.Unmarshal(data, &records); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(records, IssueRecords("app.ini", issues)) {
+			t.Errorf("Unexpected round trip %+v", records)
+		}
+		if again, _ := IssuesJSON("app.ini", issues); !bytes.Equal(again, data) {
+			t.Error("Expected stable output")
+		}
+	})
+
+	t.Run("sarif", func(t *testing.T) {
+		data, err := IssuesSARIF("rutil-lint", "app.ini", issues)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var log struct {
+			Version string `json:"version"`
+			Runs    []struct {
+				Tool struct {
+					Driver struct {
+						Name  string `json:"name"`
+						Rules []struct {
+							ID string `json:"id"`
+						} `json:"rules"`
+					} `json:"driver"`
+				} `json:"tool"`
+				Results []struct {
+					RuleID    string `json:"ruleId"`
+					Level     string `json:"level"`
+					Locations []struct {
+						PhysicalLocation struct {
+							ArtifactLocation struct {
+								URI string `json:"uri"`
+							} `json:"artifactLocation"`
+							Region struct {
+								StartLine   int `json:"startLine"`
+								StartColumn int `json:"startColumn"`
+							} `json:"region"`
+						} `json:"physicalLocation"`
+					} `json:"locations"`
+				} `json:"results"`
+			} `json:"runs"`
+		}
+		if err := json.Unmarshal(data, &log); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if log.Version != "2.1.0" || len(log.Runs) != 1 || log.Runs[0].Tool.Driver.Name != "rutil-lint" {
+			t.Fatalf("Unexpected log %s", data)
+		}
+		run := log.Runs[0]
+		if len(run.Tool.Driver.Rules) != 2 || run.Tool.Driver.Rules[0].ID != RuleSecretValue {
+			t.Errorf("Unexpected rules %+v", run.Tool.Driver.Rules)
+		}
+		levels := []string{run.Results[0].Level, run.Results[1].Level, run.Results[2].Level}
+		if !reflect.DeepEqual(levels, []string{"warning", "warning", "error"}) {
+			t.Errorf("Unexpected levels %v", levels)
+		}
+		loc := run.Results[0].Locations[0].PhysicalLocation
+		if loc.ArtifactLocation.URI != "app.ini" || loc.Region.StartLine != 12 || loc.Region.StartColumn != 5 {
+			t.Errorf("Unexpected location %+v", loc)
+		}
+	})
+}
@@ -0,0 +1,240 @@
+package fileops
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-serr/serr"
+)
+
+// Lint rule names, used to disable rules and reported in the "rule" field of findings
+const (
+	RuleSyntax             = "syntax"               // unparseable section headers and empty keys
+	RuleDuplicateKey       = "duplicate-key"        // a key set twice in the same section
+	RuleKeyOutsideSection  = "key-outside-section"  // ini keys before any section header
+	RuleUnusedDefault      = "unused-default"       // [DEFAULT] keys overridden by every section
+	RuleQuotedWhitespace   = "quoted-whitespace"    // trailing whitespace inside a quoted value
+	RuleMismatchedQuote    = "mismatched-quote"     // a quote that is never closed, or never opened
+	RuleTextAfterQuote     = "text-after-quote"     // text other than a comment after a closing quote
+	RuleHashInURL          = "hash-in-unquoted-url" // a '#' in an unquoted URL, which starts a comment
+	RuleNonPOSIXEnvVarName = "non-posix-env-name"   // env var names other than [A-Za-z_][A-Za-z0-9_]*
+	RuleSecretValue        = "secret-value"         // values that look like passwords, tokens or keys
+)
+
+// LintOptions controls LintIni and LintEnv
+type LintOptions struct {
+	Disabled map[string]bool // rule names to skip
+}
+
+var (
+	posixEnvName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	secretKey    = regexp.MustCompile(`(?i)(pass(word|wd)?|secret|token|api[_-]?key|private[_-]?key|credential)`)
+	secretValue  = regexp.MustCompile(`AKIA[0-9A-Z]{16}|-----BEGIN [A-Z ]*PRIVATE KEY-----|gh[pousr]_[A-Za-z0-9]{36}|xox[abprs]-[A-Za-z0-9-]+`)
+)
+
+// LintIni checks an ini document, returning a finding for each problem found, ordered by line.
+// Findings are issues with "rule", "severity", "lineNbr" and "col" fields
+func LintIni(src []byte, optOpts ...LintOptions) (findings []serr.SErr) {
+	l := newLinter(optOpts)
+	doc, _, _ := ParseIniDoc(bytes.NewReader(src))
+
+	seen := make(map[string]int, 16)
+	for _, line := range doc.Lines {
+		l.checkLine(line, seen)
+		if line.Kind == LineKeyValue && line.Section == "" && line.Key != "" {
+			l.add(RuleKeyOutsideSection, serr.Severity.Error, "Key is outside any section", line, 1)
+		}
+	}
+
+	// [DEFAULT] keys are inherited by every section, so they are unused if all sections override them
+	attrsBySection := doc.AsMapOfSections()
+	positions := doc.Positions()
+	for key := range attrsBySection["DEFAULT"] {
+		used := false
+		for section, attrs := range attrsBySection {
+			if _, overridden := attrs[key]; section != "DEFAULT" && !overridden {
+				used = true
+				break
+			}
+		}
+		if !used {
+			l.add(RuleUnusedDefault, serr.Severity.Info, "[DEFAULT] key is overridden by every section",
+				IniLine{Key: key, LineNbr: positions.Of("DEFAULT", key)}, 1)
+		}
+	}
+
+	return l.sorted()
+}
+
+// LintEnv checks a .env file, returning findings as LintIni does
+func LintEnv(src []byte, optOpts ...LintOptions) (findings []serr.SErr) {
+	l := newLinter(optOpts)
+
+	seen := make(map[string]int, 16)
+	lines := strings.Split(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := parseIniLine(lines[i], "")
+		line.LineNbr = i + 1
+		line.Key = trimExport(line.Key)
+		if line.Kind != LineKeyValue {
+			continue // lines without '=' are ignored in .env files
+		}
+
+		// A quoted value continuing on later lines, as ParseEnv reads it
+		if val := strings.TrimSpace(line.Value); line.Quote == 0 && len(val) > 1 && (val[0] == '"' || val[0] == '\'') {
+			if end, idx := envQuoteEnd(lines, i, val[0]); end != -1 {
+				line.Quote, line.Value = val[0], envMultiLineValue(lines, i, end, idx, val[0])
+				i = end
+			}
+		}
+		l.checkLine(line, seen)
+
+		if line.Key != "" && !posixEnvName.MatchString(line.Key) {
+			l.add(RuleNonPOSIXEnvVarName, serr.Severity.Warn, "Variable name is not POSIX compliant", line, 1)
+		}
+	}
+
+	return l.sorted()
+}
+
+// LintFile lints filespec, as a .env file if its name starts or ends with ".env", otherwise as ini.
+// Findings include a "file" field
+func LintFile(filespec string, optOpts ...LintOptions) (findings []serr.SErr, err error) {
+	src, err := os.ReadFile(filespec)
+	if err != nil {
+		return nil, serr.Wrap(err, "Error reading: "+filespec)
+	}
+
+	base := filepath.Base(filespec)
+	if strings.HasPrefix(base, ".env") || strings.HasSuffix(base, ".env") {
+		findings = LintEnv(src, optOpts...)
+	} else {
+		findings = LintIni(src, optOpts...)
+	}
+
+	for i := range findings {
+		findings[i].AppendKeyValPairs("file", filespec)
+	}
+	return
+}
+
+type finding struct {
+	lineNbr int
+	col     int
+	issue   serr.SErr
+}
+
+type linter struct {
+	opts     LintOptions
+	findings []finding
+}
+
+func newLinter(optOpts []LintOptions) *linter {
+	l := &linter{}
+	if len(optOpts) > 0 {
+		l.opts = optOpts[0]
+	}
+	return l
+}
+
+// checkLine applies the rules common to ini and .env files. seen tracks the line numbers of keys
+func (l *linter) checkLine(line IniLine, seen map[string]int) {
+	raw := line.Raw
+
+	switch line.Kind {
+	case LineOther:
+		if strings.HasPrefix(strings.TrimSpace(raw), "[") {
+			l.add(RuleSyntax, serr.Severity.Error, "Mismatched '[' ']' or empty section", line, 1)
+		}
+		return
+	case LineKeyValue:
+	default:
+		return
+	}
+
+	if line.Key == "" {
+		l.add(RuleSyntax, serr.Severity.Error, "Key is empty", line, 1)
+		return
+	}
+
+	sectKey := line.Section + sectKeySep + line.Key
+	if first, ok := seen[sectKey]; ok {
+		l.add(RuleDuplicateKey, serr.Severity.Warn, "Key is already set on line "+strconv.Itoa(first), line, 1,
+			"firstLineNbr", strconv.Itoa(first))
+	} else {
+		seen[sectKey] = line.LineNbr
+	}
+
+	eq := strings.IndexByte(raw, '=')
+	valStart := eq + 1 + len(raw[eq+1:]) - len(strings.TrimLeft(raw[eq+1:], " \t"))
+	val := strings.TrimSpace(raw[eq+1:])
+	col := valStart + 1
+
+	switch {
+	case line.Quote == 0 && val != "" && (val[0] == '"' || val[0] == '\''):
+		l.add(RuleMismatchedQuote, serr.Severity.Error, "Quote is never closed", line, col)
+	case line.Quote == 0 && line.Value != "":
+		if last := line.Value[len(line.Value)-1:]; (last == `"` || last == "'") && strings.Count(line.Value, last) == 1 {
+			l.add(RuleMismatchedQuote, serr.Severity.Warn, "Value ends with a quote that was never opened",
+				line, valStart+len(line.Value))
+		}
+	}
+
+	if line.Quote != 0 && line.Value != strings.TrimRight(line.Value, " \t") {
+		l.add(RuleQuotedWhitespace, serr.Severity.Warn, "Quoted value has trailing whitespace", line, col)
+	}
+
+	if line.Quote != 0 && line.Comment != "" && !strings.HasPrefix(line.Comment, "#") {
+		l.add(RuleTextAfterQuote, serr.Severity.Warn, "Text after closing quote is ignored", line,
+			strings.LastIndex(raw, line.Comment)+1)
+	}
+
+	if line.Quote == 0 && line.Comment != "" && strings.Contains(line.Value, "://") {
+		if hash := valStart + strings.IndexByte(val, '#'); hash > 0 && raw[hash-1] != ' ' && raw[hash-1] != '\t' {
+			l.add(RuleHashInURL, serr.Severity.Warn, "'#' in unquoted URL starts a comment, truncating the value",
+				line, hash+1)
+		}
+	}
+
+	if line.Value != "" && !strings.HasPrefix(line.Value, "$") &&
+		(secretKey.MatchString(line.Key) || secretValue.MatchString(line.Value)) {
+		l.add(RuleSecretValue, serr.Severity.Warn, "Value looks like a secret", line, col)
+	}
+}
+
+// add records a finding for rule unless it is disabled
+func (l *linter) add(rule, severity, msg string, line IniLine, col int, fields ...string) {
+	if l.opts.Disabled[rule] {
+		return
+	}
+
+	fields = append([]string{"rule", rule, "severity", severity,
+		"lineNbr", fmt.Sprintf("%d", line.LineNbr), "col", fmt.Sprintf("%d", col)}, fields...)
+	if line.Key != "" {
+		fields = append(fields, "key", line.Key)
+	}
+	if line.Section != "" {
+		fields = append(fields, "section", line.Section)
+	}
+	l.findings = append(l.findings, finding{lineNbr: line.LineNbr, col: col, issue: serr.NewSErr(msg, fields...)})
+}
+
+// sorted returns the findings ordered by line then column
+func (l *linter) sorted() (findings []serr.SErr) {
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		if l.findings[i].lineNbr != l.findings[j].lineNbr {
+			return l.findings[i].lineNbr < l.findings[j].lineNbr
+		}
+		return l.findings[i].col < l.findings[j].col
+	})
+	for _, f := range l.findings {
+		findings = append(findings, f.issue)
+	}
+	return
+}
@@ -1,90 +1,65 @@
 package fileops
 
 import (
-	"bufio"
 	"fmt"
 	"os"
-	"strings"
 
 	"github.com/go-serr/serr"
 )
 
-// EnvFromFile reads a `*.env` style file and loads into the environment
+// EnvFromFile reads a `*.env` style file and loads into the environment, overriding variables
+// that are already set (godotenv's Overload). See EnvFromFileNoOverride to keep them, and
+// ParseEnvFile to read the variables without changing the environment
 func EnvFromFile(filespec string) (issues []serr.SErr, err error) {
-	file, err := os.Open(filespec)
+	return EnvFromFileWithOptions(filespec, EnvOptions{})
+}
+
+// EnvFromFileWithOptions is EnvFromFile parsing the file with opts, e.g. to expand variables
+func EnvFromFileWithOptions(filespec string, opts EnvOptions) (issues []serr.SErr, err error) {
+	pairs, issues, err := ParseEnvFile(filespec, opts)
 	if err != nil {
-		return issues, serr.Wrap(err, "Error reading: "+filespec)
+		return issues, err
 	}
 
-	scanner := bufio.NewScanner(file)
-
-	lineNbr := 0
-	for scanner.Scan() { // splits on lines by default
-		line := strings.TrimSpace(scanner.Text())
-		lineNbr++
-
-		if line == "" {
-			continue
-		}
-
-		if strings.HasPrefix(line, "#") { // skip lines starting with a comment
-			continue
-		}
-
-		// Keys and Values
-		bef, aft, fnd := strings.Cut(line, "=")
-		if !fnd {
-			continue
-		}
+	_, applyIssues := ApplyEnvPairs(pairs, true)
+	return append(issues, applyIssues...), nil
+}
 
-		key := strings.TrimSpace(bef)
-		if key == "" {
-			issues = append(issues, serr.NewSErr("key is empty", "line", line,
-				"lineNbr", fmt.Sprintf("%d", lineNbr)))
-			continue
-		}
+// EnvFromFileNoOverride reads a `*.env` style file and loads into the environment only the
+// variables that are not already set (godotenv's Load), so that settings from the orchestrator
+// win over a stray file. It returns the keys skipped because they were already set
+func EnvFromFileNoOverride(filespec string, optOpts ...EnvOptions) (skipped []string, issues []serr.SErr, err error) {
+	pairs, issues, err := ParseEnvFile(filespec, optOpts...)
+	if err != nil {
+		return skipped, issues, err
+	}
 
-		val := strings.TrimSpace(aft)
-		if val == "" {
-			issues = append(issues, serr.NewSErr("Value is empty", "line", line,
-				"lineNbr", fmt.Sprintf("%d", lineNbr)))
-			continue
-		}
+	skipped, applyIssues := ApplyEnvPairs(pairs, false)
+	return skipped, append(issues, applyIssues...), nil
+}
 
-		// Check for delimiters and comments
-		if len(val) > 1 {
-			// First check if value has surrounding quotes as **quotes have the highest precedence**
-			// Don't trim after delimiters removed to allow spaces in values
-			if strings.HasPrefix(val, `'`) {
-				if idx := strings.IndexByte(val[1:], '\''); idx != -1 {
-					val = val[1 : idx+1]
-				}
-			} else if strings.HasPrefix(val, `"`) {
-				if idx := strings.IndexByte(val[1:], '"'); idx != -1 {
-					val = val[1 : idx+1]
-				}
-				// For comments we do want to trim space
-			} else if x := strings.IndexByte(val, '#'); x != -1 {
-				val = strings.TrimSpace(val[:x])
+// ApplyEnvPairs sets pairs in the environment, in order. Unless override is set, keys already
+// set before the call are left alone and returned in skipped, once each in the order read.
+// Keys set twice by pairs take the last value either way
+func ApplyEnvPairs(pairs []EnvPair, override bool) (skipped []string, issues []serr.SErr) {
+	preset := make(map[string]bool, len(pairs))
+	if !override {
+		for _, pair := range pairs {
+			if _, ok := os.LookupEnv(pair.Key); ok && !preset[pair.Key] {
+				preset[pair.Key] = true
+				skipped = append(skipped, pair.Key)
 			}
 		}
+	}
 
-		if val == "" {
-			issues = append(issues, serr.NewSErr("Value is empty", "line", line,
-				"lineNbr", fmt.Sprintf("%d", lineNbr)))
+	for _, pair := range pairs {
+		if preset[pair.Key] {
 			continue
 		}
-
-		err = os.Setenv(key, val)
-		if err != nil {
-			issues = append(issues, serr.NewSErr("Error setting environment variable", "key", key, "val", val,
-				"line", line, "lineNbr", fmt.Sprintf("%d", lineNbr)))
+		if err := os.Setenv(pair.Key, pair.Value); err != nil {
+			issues = append(issues, serr.NewSErr("Error setting environment variable", "key", pair.Key,
+				"val", pair.Value, "lineNbr", fmt.Sprintf("%d", pair.LineNbr)))
 		}
 	}
-
-	if err := scanner.Err(); err != nil {
-		return issues, serr.Wrap(err, "Error while scanning ", filespec)
-	}
-
 	return
 }
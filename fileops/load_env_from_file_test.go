@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/go-serr/serr"
 )
 
 func TestEnvFromFile(t *testing.T) {
@@ -146,3 +148,62 @@ func TestEnvFromFile(t *testing.T) {
 		}
 	})
 }
+
+func TestEnvFromFileNoOverride(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "test.env")
+	err := os.WriteFile(envFile, []byte("PRESET=from file\nUNSET=first\nUNSET=second\nPRESET=again\n"), 0644)
+	if err != nil {
+		t.Fatalf("failed to create test env file: %v", err)
+	}
+
+	t.Setenv("PRESET", "from orchestrator")
+	t.Setenv("UNSET", "")
+	os.Unsetenv("UNSET")
+
+	skipped, issues, err := EnvFromFileNoOverride(envFile)
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("unexpected error %v or issues %v", err, issues)
+	}
+	if len(skipped) != 1 || skipped[0] != "PRESET" {
+		t.Errorf("expected PRESET to be skipped, got %v", skipped)
+	}
+	if value := os.Getenv("PRESET"); value != "from orchestrator" {
+		t.Errorf("environment variable PRESET = %s, want from orchestrator", value)
+	}
+	if value := os.Getenv("UNSET"); value != "second" {
+		t.Errorf("environment variable UNSET = %s, want second", value)
+	}
+
+	// Overriding takes the file's values
+	if _, err := EnvFromFile(envFile); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if value := os.Getenv("PRESET"); value != "again" {
+		t.Errorf("environment variable PRESET = %s, want again", value)
+	}
+
+	if _, _, err := EnvFromFileNoOverride("non-existent-file.env"); err == nil {
+		t.Error("expected error for non-existent file, got nil")
+	}
+}
+
+// EnvFromFile keeps the signature it has always had, so that it can still be passed as a value
+var _ func(string) ([]serr.SErr, error) = EnvFromFile
+
+func TestEnvFromFileWithOptions(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "test.env")
+	err := os.WriteFile(envFile, []byte("WITH_OPTS_HOST=db\nWITH_OPTS_URL=postgres://${WITH_OPTS_HOST}/app\n"), 0644)
+	if err != nil {
+		t.Fatalf("failed to create test env file: %v", err)
+	}
+	t.Setenv("WITH_OPTS_HOST", "")
+	t.Setenv("WITH_OPTS_URL", "")
+
+	issues, err := EnvFromFileWithOptions(envFile, EnvOptions{Expand: true})
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("unexpected error %v or issues %v", err, issues)
+	}
+	if value := os.Getenv("WITH_OPTS_URL"); value != "postgres://db/app" {
+		t.Errorf("environment variable WITH_OPTS_URL = %s, want postgres://db/app", value)
+	}
+}
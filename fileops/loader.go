@@ -0,0 +1,161 @@
+package fileops
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-serr/serr"
+)
+
+// Layers of configuration in increasing order of precedence
+const (
+	LayerDefaults = "defaults"
+	LayerFile     = "file"
+	LayerEnv      = "env"
+	LayerFlag     = "flag"
+)
+
+// Source records where a configuration value came from
+type Source struct {
+	Layer   string // one of the Layer* constants
+	Name    string // file path, env var name or flag name. Empty for defaults
+	LineNbr int    // line number within a file
+}
+
+// LoadedConfig is the merged view produced by Loader.Load
+type LoadedConfig struct {
+	Sections   map[string]map[string]string
+	Provenance map[string]Source // keyed by "section::key"
+}
+
+// Loader merges configuration layers. In increasing order of precedence these are
+// Defaults, each of Files in order, environment variables and finally flags
+type Loader struct {
+	Defaults map[string]map[string]string
+
+	// Files are ini files such as /etc/app.ini, ~/.config/app.ini and ./app.ini.
+	// Missing files are skipped and a leading "~/" is expanded to the home directory
+	Files []string
+
+	// EnvPrefix enables the environment layer. A var named like PREFIX_SECTION_KEY
+	// (see EnvVarName) overrides section::key, which must come from Defaults or Files
+	EnvPrefix string
+
+	// EnvNaming, if set, replaces the naming of EnvVarName, e.g. &EnvNaming{} for
+	// PREFIX_SECTION__KEY. Vars with the prefix may then add keys as with OverlayEnv
+	EnvNaming *EnvNaming
+
+	// FlagSet enables the flag layer. Flags named like section.key (see FlagName)
+	// which were set on the command line override section::key
+	FlagSet *flag.FlagSet
+}
+
+// Load reads and merges all layers
+func (l Loader) Load() (cfg LoadedConfig, issues []serr.SErr, err error) {
+	cfg = LoadedConfig{
+		Sections:   make(map[string]map[string]string, 4),
+		Provenance: make(map[string]Source, 16),
+	}
+
+	for section, attrs := range l.Defaults {
+		for key, val := range attrs {
+			cfg.set(section, key, val, Source{Layer: LayerDefaults})
+		}
+	}
+
+	for _, filespec := range l.Files {
+		filespec, err = expandHome(filespec)
+		if err != nil {
+			return cfg, issues, err
+		}
+		if _, statErr := os.Stat(filespec); errors.Is(statErr, fs.ErrNotExist) {
+			continue
+		}
+
+		attrsBySection, positions, fileIssues, err := ReadIniWithPositions(filespec)
+		issues = append(issues, fileIssues...)
+		if err != nil {
+			return cfg, issues, serr.Wrap(err, "file", filespec)
+		}
+
+		for section, attrs := range attrsBySection {
+			for key, val := range attrs {
+				cfg.set(section, key, val, Source{Layer: LayerFile, Name: filespec, LineNbr: positions.Of(section, key)})
+			}
+		}
+	}
+
+	if l.EnvPrefix != "" && l.EnvNaming != nil {
+		// Overlay a copy so that provenance can be recorded for each key set
+		overlay := copySections(cfg.Sections)
+		for _, sectKey := range OverlayEnv(overlay, l.EnvPrefix, *l.EnvNaming) {
+			section, key, _ := strings.Cut(sectKey, sectKeySep)
+			name := l.EnvNaming.VarName(l.EnvPrefix, section, key)
+			cfg.set(section, key, overlay[section][key], Source{Layer: LayerEnv, Name: name})
+		}
+	} else if l.EnvPrefix != "" {
+		for section, attrs := range cfg.Sections {
+			for key := range attrs {
+				name := EnvVarName(l.EnvPrefix, section, key)
+				if val, ok := os.LookupEnv(name); ok {
+					cfg.set(section, key, val, Source{Layer: LayerEnv, Name: name})
+				}
+			}
+		}
+	}
+
+	if l.FlagSet != nil {
+		l.FlagSet.Visit(func(f *flag.Flag) {
+			section, key, fnd := strings.Cut(f.Name, ".")
+			if !fnd || section == "" || key == "" {
+				return
+			}
+			cfg.set(section, key, f.Value.String(), Source{Layer: LayerFlag, Name: f.Name})
+		})
+	}
+
+	return
+}
+
+// Get returns the value of key in section
+func (c LoadedConfig) Get(section, key string) (val string, ok bool) {
+	val, ok = c.Sections[section][key]
+	return
+}
+
+func (c LoadedConfig) set(section, key, val string, src Source) {
+	if c.Sections[section] == nil {
+		c.Sections[section] = make(map[string]string, 4)
+	}
+	c.Sections[section][key] = val
+	c.Provenance[section+sectKeySep+key] = src
+}
+
+// EnvVarName returns the environment variable Loader consults for section::key by default,
+// e.g. EnvVarName("APP", "database", "host") returns "APP_DATABASE_HOST".
+// Characters other than letters and digits become underscores
+func EnvVarName(prefix, section, key string) string {
+	naming := EnvNaming{Separator: "_"}
+	return naming.VarName(naming.transform(prefix), section, key)
+}
+
+// FlagName returns the flag name Loader consults for section::key, e.g. "database.host"
+func FlagName(section, key string) string {
+	return section + "." + key
+}
+
+// expandHome replaces a leading "~/" in filespec with the user's home directory
+func expandHome(filespec string) (string, error) {
+	if !strings.HasPrefix(filespec, "~/") {
+		return filespec, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filespec, serr.Wrap(err, "Error expanding: "+filespec)
+	}
+	return filepath.Join(home, filespec[2:]), nil
+}
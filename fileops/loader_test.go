@@ -0,0 +1,125 @@
+package fileops
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoader(t *testing.T) {
+	dir := t.TempDir()
+	systemIni := filepath.Join(dir, "system.ini")
+	localIni := filepath.Join(dir, "local.ini")
+
+	if err := os.WriteFile(systemIni, []byte("[database]\nhost = sys-db\nport = 5432\nuser = sys\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(localIni, []byte("[database]\n\nuser = local\n[cache]\nttl = 60s\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	t.Setenv("APP_DATABASE_PORT", "6543")
+	t.Setenv("APP_CACHE_SIZE", "100") // not a known key so ignored
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("database.host", "", "")
+	fs.String("cache.size", "", "")
+	fs.String("verbose", "", "")
+	if err := fs.Parse([]string{"-database.host=flag-db", "-verbose=true"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	loader := Loader{
+		Defaults: map[string]map[string]string{
+			"database": {"host": "localhost", "sslmode": "disable"},
+		},
+		Files:     []string{systemIni, filepath.Join(dir, "missing.ini"), localIni},
+		EnvPrefix: "APP",
+		FlagSet:   fs,
+	}
+
+	cfg, issues, err := loader.Load()
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("Unexpected err: %v, issues: %v", err, issues)
+	}
+
+	expected := map[string]map[string]string{
+		"database": {"host": "flag-db", "port": "6543", "user": "local", "sslmode": "disable"},
+		"cache":    {"ttl": "60s"},
+	}
+	if !reflect.DeepEqual(cfg.Sections, expected) {
+		t.Errorf("Expected %v, got %v", expected, cfg.Sections)
+	}
+
+	expectedProvenance := map[string]Source{
+		"database::host":    {Layer: LayerFlag, Name: "database.host"},
+		"database::port":    {Layer: LayerEnv, Name: "APP_DATABASE_PORT"},
+		"database::user":    {Layer: LayerFile, Name: localIni, LineNbr: 3},
+		"database::sslmode": {Layer: LayerDefaults},
+		"cache::ttl":        {Layer: LayerFile, Name: localIni, LineNbr: 5},
+	}
+	if !reflect.DeepEqual(cfg.Provenance, expectedProvenance) {
+		t.Errorf("Expected %v, got %v", expectedProvenance, cfg.Provenance)
+	}
+
+	if val, ok := cfg.Get("database", "user"); !ok || val != "local" {
+		t.Errorf("Expected local, got %q", val)
+	}
+
+	t.Run("bad file", func(t *testing.T) {
+		badIni := filepath.Join(dir, "bad.ini")
+		if err := os.WriteFile(badIni, []byte("key = no section\n"), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		if _, _, err := (Loader{Files: []string{badIni}}).Load(); err == nil {
+			t.Error("Expected error but got none")
+		}
+	})
+
+	t.Run("home expansion", func(t *testing.T) {
+		t.Setenv("HOME", dir)
+		cfg, _, err := Loader{Files: []string{"~/local.ini"}}.Load()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cfg.Provenance["cache::ttl"].Name != localIni {
+			t.Errorf("Expected %s, got %v", localIni, cfg.Provenance["cache::ttl"])
+		}
+	})
+}
+
+func TestEnvVarNameAndFlagName(t *testing.T) {
+	if name := EnvVarName("app", "my-db", "host.name"); name != "APP_MY_DB_HOST_NAME" {
+		t.Errorf("Unexpected env var name %q", name)
+	}
+	if name := FlagName("database", "host"); name != "database.host" {
+		t.Errorf("Unexpected flag name %q", name)
+	}
+}
+
+func TestLoaderEnvNaming(t *testing.T) {
+	t.Setenv("APP_DATABASE__HOST", "env-db")
+	t.Setenv("APP_CACHE__SIZE", "100")
+
+	cfg, _, err := Loader{
+		Defaults:  map[string]map[string]string{"database": {"host": "localhost"}},
+		EnvPrefix: "APP",
+		EnvNaming: &EnvNaming{},
+	}.Load()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]map[string]string{
+		"database": {"host": "env-db"},
+		"cache":    {"size": "100"},
+	}
+	if !reflect.DeepEqual(cfg.Sections, expected) {
+		t.Errorf("Expected %v, got %v", expected, cfg.Sections)
+	}
+	if src := cfg.Provenance["cache::size"]; src != (Source{Layer: LayerEnv, Name: "APP_CACHE__SIZE"}) {
+		t.Errorf("Unexpected provenance %v", src)
+	}
+}
@@ -0,0 +1,170 @@
+package fileops
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-serr/serr"
+)
+
+// EnvPair is a variable read from a `*.env` style file
+type EnvPair struct {
+	Key     string
+	Value   string // unquoted value
+	LineNbr int
+	Quote   byte // the quote character the value was written with, if any
+}
+
+// EnvOptions controls how `*.env` style files are read
+type EnvOptions struct {
+	// Expand expands $VAR, ${VAR}, ${VAR:-default}, ${VAR:?error} and ${VAR:+alt} (and the forms
+	// without ':', which test only whether VAR is set) in unquoted and double-quoted values, as
+	// Docker Compose does. $$ is a literal '$'. Variables resolve to earlier keys in the file, then
+	// the environment, then later keys in the file. Undefined variables and cycles are reported
+	Expand bool
+
+	// AllowColon also accepts YAML-ish `KEY: value` lines, where KEY is a POSIX variable name
+	AllowColon bool
+
+	// KeepEmpty returns keys with empty values, as in `.env.example` files, rather than
+	// reporting them as issues
+	KeepEmpty bool
+}
+
+// ParseEnv reads `*.env` style lines from r, returning the variables in the order they appear,
+// without changing the environment. A key set twice appears twice, so applying the pairs in
+// order gives the last value, as EnvFromFile does. Any `export` prefix is ignored, and quoted
+// values may span lines. Empty keys, empty values unless kept, and quotes never closed are reported
+// as issues. Variables in values are expanded if the options given ask for it (see EnvOptions)
+func ParseEnv(r io.Reader, optOpts ...EnvOptions) (pairs []EnvPair, issues []serr.SErr, err error) {
+	opts := EnvOptions{}
+	if len(optOpts) > 0 {
+		opts = optOpts[0]
+	}
+
+	scanner := bufio.NewScanner(r)
+
+	var lines []string
+	for scanner.Scan() { // splits on lines by default
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return pairs, issues, serr.Wrap(err, "Error while scanning env")
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		lineNbr := i + 1
+
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") { // skip lines starting with a comment
+			continue
+		}
+
+		// Keys and Values, as `KEY=value`, `export KEY=value` or optionally `KEY: value`
+		line = trimExport(line)
+		bef, aft, fnd := strings.Cut(line, "=")
+		if opts.AllowColon {
+			if b, a, f := strings.Cut(line, ":"); f && (!fnd || len(b) < len(bef)) && posixEnvName.MatchString(b) {
+				bef, aft, fnd = b, a, true
+			}
+		}
+		if !fnd {
+			continue // including bash's `export KEY`
+		}
+
+		key := strings.TrimSpace(bef)
+		if key == "" {
+			issues = append(issues, serr.NewSErr("key is empty", "line", line,
+				"lineNbr", fmt.Sprintf("%d", lineNbr)))
+			continue
+		}
+
+		val := strings.TrimSpace(aft)
+
+		// Check for delimiters and comments
+		var quote byte
+		if len(val) > 1 {
+			// First check if value has surrounding quotes as **quotes have the highest precedence**
+			// Don't trim after delimiters removed to allow spaces in values
+			if val[0] == '\'' || val[0] == '"' {
+				if idx := strings.IndexByte(val[1:], val[0]); idx != -1 {
+					quote, val = val[0], val[1:idx+1]
+				} else if end, idx := envQuoteEnd(lines, i, val[0]); end != -1 {
+					// The value continues up to the closing quote on a later line
+					quote, val = val[0], envMultiLineValue(lines, i, end, idx, val[0])
+					i = end
+				} else {
+					issues = append(issues, serr.NewSErr("Quote is never closed", "line", line,
+						"lineNbr", fmt.Sprintf("%d", lineNbr)))
+				}
+				// For comments we do want to trim space
+			} else if x := strings.IndexByte(val, '#'); x != -1 {
+				val = strings.TrimSpace(val[:x])
+			}
+		}
+
+		if val == "" && !opts.KeepEmpty {
+			issues = append(issues, serr.NewSErr("Value is empty", "line", line,
+				"lineNbr", fmt.Sprintf("%d", lineNbr)))
+			continue
+		}
+
+		pairs = append(pairs, EnvPair{Key: key, Value: val, LineNbr: lineNbr, Quote: quote})
+	}
+
+	if opts.Expand {
+		issues = append(issues, expandEnvPairs(pairs, os.LookupEnv)...)
+	}
+	return
+}
+
+// envQuoteEnd returns the index of the first line after line i containing quote, and the index
+// of the quote in it, or -1 if there is none
+func envQuoteEnd(lines []string, i int, quote byte) (end, idx int) {
+	for end = i + 1; end < len(lines); end++ {
+		if idx = strings.IndexByte(lines[end], quote); idx != -1 {
+			return end, idx
+		}
+	}
+	return -1, -1
+}
+
+// envMultiLineValue returns the value quoted from line i to the closing quote at idx on line end,
+// byte for byte. The quote isn't closed on line i, so the opening quote is its last quote
+func envMultiLineValue(lines []string, i, end, idx int, quote byte) string {
+	parts := []string{lines[i][strings.LastIndexByte(lines[i], quote)+1:]}
+	parts = append(parts, lines[i+1:end]...)
+	return strings.Join(append(parts, lines[end][:idx]), "\n")
+}
+
+// trimExport removes a leading `export` from line, as bash files meant to be sourced use
+func trimExport(line string) string {
+	if rest, ok := strings.CutPrefix(line, "export"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+		return strings.TrimSpace(rest)
+	}
+	return line
+}
+
+// ParseEnvFile reads a `*.env` style file as ParseEnv does
+func ParseEnvFile(filespec string, optOpts ...EnvOptions) (pairs []EnvPair, issues []serr.SErr, err error) {
+	file, err := os.Open(filespec)
+	if err != nil {
+		return pairs, issues, serr.Wrap(err, "Error reading: "+filespec)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	pairs, issues, err = ParseEnv(file, optOpts...)
+	if err != nil {
+		return pairs, issues, serr.Wrap(err, "file", filespec)
+	}
+	return
+}
@@ -0,0 +1,132 @@
+package fileops
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-serr/serr"
+)
+
+// sectKeySep separates section and key in flattened keys such as those returned by ReadIni
+const sectKeySep = "::"
+
+// Positions maps where things were found in an ini file to their line numbers.
+// Section headers are stored under the section name and keys under "section::key"
+type Positions map[string]int
+
+// Of returns the line number of key in section, or 0 if unknown
+func (p Positions) Of(section, key string) int {
+	return p[section+sectKeySep+key]
+}
+
+// OfSection returns the line number of the section header, or 0 if unknown
+func (p Positions) OfSection(section string) int {
+	return p[section]
+}
+
+// ReadIniWithPositions reads an ini file like ReadIniAsMapOfSections, additionally
+// returning the line number of every section header and key.
+// Repeated sections are merged rather than replaced, and later keys win
+func ReadIniWithPositions(filespec string) (AttributesBySection map[string]map[string]string,
+	positions Positions, issues []serr.SErr, err error) {
+	AttributesBySection = make(map[string]map[string]string, 4)
+	positions = make(Positions, 16)
+
+	file, err := os.Open(filespec)
+	if err != nil {
+		return AttributesBySection, positions, issues, serr.Wrap(err, "Error reading: "+filespec)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	currSection := ""
+	scanner := bufio.NewScanner(file)
+
+	lineNbr := 0
+	for scanner.Scan() { // splits on lines by default
+		line := strings.TrimSpace(scanner.Text())
+		lineNbr++
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Check for Section
+		if strings.HasPrefix(line, "[") {
+			b, _, f := strings.Cut(line, "]")
+			if !f {
+				issues = append(issues, serr.NewSErr("Mismatched '['  ']'", "line", line,
+					"lineNbr", fmt.Sprintf("%d", lineNbr)))
+				continue
+			}
+			if len(b) <= 1 {
+				issues = append(issues, serr.NewSErr("Section empty", "line", line,
+					"lineNbr", fmt.Sprintf("%d", lineNbr)))
+				continue
+			}
+			currSection = b[1:]
+			if _, ok := AttributesBySection[currSection]; !ok {
+				AttributesBySection[currSection] = make(map[string]string, 4)
+				positions[currSection] = lineNbr
+			}
+			continue
+		}
+
+		if currSection == "" {
+			return AttributesBySection, positions, issues, serr.NewSErr("Missing section header",
+				"line", line, "lineNbr", fmt.Sprintf("%d", lineNbr))
+		}
+
+		// Keys and Values
+		bef, aft, fnd := strings.Cut(line, "=")
+		if !fnd {
+			continue
+		}
+
+		key := strings.TrimSpace(bef)
+		if key == "" {
+			issues = append(issues, serr.NewSErr("key is empty", "line", line, "lineNbr", fmt.Sprintf("%d", lineNbr)))
+			continue
+		}
+
+		// Don't make an issue of empty values
+		val := unquoteValue(strings.TrimSpace(aft))
+		if val == "" {
+			continue
+		}
+
+		AttributesBySection[currSection][key] = val
+		positions[currSection+sectKeySep+key] = lineNbr
+	}
+
+	if err := scanner.Err(); err != nil {
+		return AttributesBySection, positions, issues, serr.Wrap(err, "Error while scanning: ", filespec)
+	}
+
+	return
+}
+
+// unquoteValue applies the quoting and comment rules shared by the ini and env readers
+// to an already trimmed value. Quotes have the highest precedence
+func unquoteValue(val string) string {
+	if len(val) <= 1 {
+		return val
+	}
+	// Don't trim after delimiters removed to allow spaces in values
+	if strings.HasPrefix(val, `'`) {
+		if idx := strings.IndexByte(val[1:], '\''); idx != -1 {
+			return val[1 : idx+1]
+		}
+	} else if strings.HasPrefix(val, `"`) {
+		if idx := strings.IndexByte(val[1:], '"'); idx != -1 {
+			return val[1 : idx+1]
+		}
+		// For comments we do want to trim space
+	} else if x := strings.IndexByte(val, '#'); x != -1 {
+		return strings.TrimSpace(val[:x])
+	}
+	return val
+}
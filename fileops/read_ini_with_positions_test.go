@@ -0,0 +1,103 @@
+package fileops
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadIniWithPositions(t *testing.T) {
+	tests := []struct {
+		name              string
+		content           string
+		expectedMap       map[string]map[string]string
+		expectedPositions Positions
+		expectError       bool
+		expectedIssues    int
+	}{
+		{
+			name: "sections and keys",
+			content: `[section1]
+key1 = value1
+# comment
+key2 = "quoted # value"
+
+[section2]
+key3 = value3 # comment`,
+			expectedMap: map[string]map[string]string{
+				"section1": {"key1": "value1", "key2": "quoted # value"},
+				"section2": {"key3": "value3"},
+			},
+			expectedPositions: Positions{
+				"section1": 1, "section1::key1": 2, "section1::key2": 4,
+				"section2": 6, "section2::key3": 7,
+			},
+		},
+		{
+			name: "repeated section is merged",
+			content: `[a]
+x = 1
+[b]
+y = 2
+[a]
+z = 3`,
+			expectedMap: map[string]map[string]string{
+				"a": {"x": "1", "z": "3"},
+				"b": {"y": "2"},
+			},
+			expectedPositions: Positions{"a": 1, "a::x": 2, "b": 3, "b::y": 4, "a::z": 6},
+		},
+		{
+			name: "bad section headers and empty key are issues",
+			content: `[a
+[]
+[b]
+= value`,
+			expectedMap:       map[string]map[string]string{"b": {}},
+			expectedPositions: Positions{"b": 3},
+			expectedIssues:    3,
+		},
+		{
+			name:              "missing section",
+			content:           `key1 = value1`,
+			expectedMap:       map[string]map[string]string{},
+			expectedPositions: Positions{},
+			expectError:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filespec := filepath.Join(t.TempDir(), "test.ini")
+			if err := os.WriteFile(filespec, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			results, positions, issues, err := ReadIniWithPositions(filespec)
+
+			if tt.expectError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if len(issues) != tt.expectedIssues {
+				t.Errorf("Expected %d issues, got %d", tt.expectedIssues, len(issues))
+			}
+			if !reflect.DeepEqual(results, tt.expectedMap) {
+				t.Errorf("Results don't match\nExpected: %v\nGot: %v", tt.expectedMap, results)
+			}
+			if !reflect.DeepEqual(positions, tt.expectedPositions) {
+				t.Errorf("Positions don't match\nExpected: %v\nGot: %v", tt.expectedPositions, positions)
+			}
+		})
+	}
+
+	t.Run("Of and OfSection", func(t *testing.T) {
+		p := Positions{"db": 3, "db::host": 4}
+		if p.OfSection("db") != 3 || p.Of("db", "host") != 4 || p.Of("db", "port") != 0 {
+			t.Errorf("Unexpected lookups from %v", p)
+		}
+	})
+}
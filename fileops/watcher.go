@@ -0,0 +1,194 @@
+package fileops
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-serr/serr"
+)
+
+// LoadFunc reads a config file as a map of sections to a map of key values
+type LoadFunc func(filespec string) (map[string]map[string]string, []serr.SErr, error)
+
+// IniLoadFunc is the default LoadFunc of a Watcher
+func IniLoadFunc(filespec string) (map[string]map[string]string, []serr.SErr, error) {
+	attrsBySection, _, issues, err := ReadIniWithPositions(filespec)
+	return attrsBySection, issues, err
+}
+
+// EnvLoadSection is the section EnvLoadFunc returns the variables of a .env file in
+const EnvLoadSection = "env"
+
+// EnvLoadFunc is a LoadFunc for `*.env` style files, returning the variables as read by
+// ParseEnvFile in the single section EnvLoadSection, so their changes are keyed "env::KEY".
+// It never changes the environment
+func EnvLoadFunc(filespec string) (map[string]map[string]string, []serr.SErr, error) {
+	pairs, issues, err := ParseEnvFile(filespec)
+	if err != nil {
+		return nil, issues, err
+	}
+
+	attrs := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		attrs[pair.Key] = pair.Value
+	}
+	return map[string]map[string]string{EnvLoadSection: attrs}, issues, nil
+}
+
+// Change is a changed "section::key" value. Old is empty for added keys and New for removed keys
+type Change struct {
+	Key     string
+	Old     string
+	New     string
+	Added   bool
+	Removed bool
+}
+
+// Watcher polls a config file, reloading it when it changes and notifying subscribers of
+// the changed values. Files replaced by rename, as editors and Kubernetes ConfigMap symlink
+// swaps do, are detected. If a reload fails or has issues the previous good config is kept
+type Watcher struct {
+	filespec string
+	load     LoadFunc
+
+	Interval time.Duration // how often to poll. Defaults to 1s
+	Debounce time.Duration // how long the file must be unchanged before reloading. Defaults to 100ms
+
+	// OnError is called when a reload fails or has issues. Optional
+	OnError func(err error, issues []serr.SErr)
+
+	// AcceptIssues applies a reload that has issues but no error, as when a .env file is
+	// known to have empty values. By default such a reload keeps the previous good config
+	AcceptIssues bool
+
+	pollMu      sync.Mutex // serializes Poll
+	mu          sync.Mutex
+	current     map[string]map[string]string
+	info        os.FileInfo
+	subscribers []func(sections map[string]map[string]string, changes []Change)
+}
+
+// NewWatcher loads filespec with load, or IniLoadFunc if not given, returning a Watcher
+// holding the result. Call Run to start watching
+func NewWatcher(filespec string, optLoad ...LoadFunc) (w *Watcher, issues []serr.SErr, err error) {
+	w = &Watcher{filespec: filespec, load: IniLoadFunc, Interval: time.Second, Debounce: 100 * time.Millisecond}
+	if len(optLoad) > 0 && optLoad[0] != nil {
+		w.load = optLoad[0]
+	}
+
+	w.info, err = os.Stat(filespec)
+	if err != nil {
+		return nil, issues, serr.Wrap(err, "Error reading: "+filespec)
+	}
+
+	w.current, issues, err = w.load(filespec)
+	if err != nil {
+		return nil, issues, err
+	}
+	return
+}
+
+// Current returns the last good config. It must not be modified
+func (w *Watcher) Current() map[string]map[string]string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// Subscribe registers fn to be called with the new config and its changes after each reload
+// that changes any value. The sections passed must not be modified
+func (w *Watcher) Subscribe(fn func(sections map[string]map[string]string, changes []Change)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// Run polls until ctx is done
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Poll(ctx)
+		}
+	}
+}
+
+// Poll checks the file once, reloading it and notifying subscribers if it has changed.
+// It returns true if subscribers were notified. It may be called while Run is running,
+// in which case the calls take turns
+func (w *Watcher) Poll(ctx context.Context) (notified bool) {
+	w.pollMu.Lock()
+	defer w.pollMu.Unlock()
+
+	w.mu.Lock()
+	prev := w.info
+	w.mu.Unlock()
+
+	info, err := os.Stat(w.filespec) // follows symlinks
+	if err != nil || !fileChanged(prev, info) {
+		return false // a missing file may be mid-replace, so wait for it to reappear
+	}
+
+	// Debounce until the file stops changing
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(w.Debounce):
+		}
+		next, err := os.Stat(w.filespec)
+		if err != nil {
+			return false
+		}
+		if !fileChanged(info, next) {
+			break
+		}
+		info = next
+	}
+
+	sections, issues, err := w.load(w.filespec)
+	if (err != nil || len(issues) > 0) && w.OnError != nil {
+		w.OnError(err, issues)
+	}
+
+	w.mu.Lock()
+	w.info = info
+	if err != nil || (len(issues) > 0 && !w.AcceptIssues) {
+		w.mu.Unlock()
+		return false // keep the previous good config
+	}
+	changes := diffSections(w.current, sections)
+	if len(changes) == 0 {
+		w.mu.Unlock()
+		return false
+	}
+	w.current = sections
+	subscribers := append([]func(map[string]map[string]string, []Change){}, w.subscribers...)
+	w.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(sections, changes)
+	}
+	return true
+}
+
+// fileChanged reports whether the file described by prev has been modified or replaced
+func fileChanged(prev, curr os.FileInfo) bool {
+	return !os.SameFile(prev, curr) || !prev.ModTime().Equal(curr.ModTime()) || prev.Size() != curr.Size()
+}
+
+// diffSections returns the changes from old to new, sorted by key
+func diffSections(old, new map[string]map[string]string) (changes []Change) {
+	for _, kd := range DiffIni(old, new).Keys {
+		changes = append(changes, Change{Key: kd.Section + sectKeySep + kd.Key, Old: kd.Old, New: kd.New,
+			Added: kd.Kind == DiffAdded, Removed: kd.Kind == DiffRemoved})
+	}
+	return
+}
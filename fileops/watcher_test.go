@@ -0,0 +1,257 @@
+package fileops
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-serr/serr"
+)
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	filespec := filepath.Join(dir, "app.ini")
+	if err := os.WriteFile(filespec, []byte("[db]\nhost = a\nport = 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	w, issues, err := NewWatcher(filespec)
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("Unexpected err: %v, issues: %v", err, issues)
+	}
+	w.Debounce = time.Millisecond
+
+	var errs []error
+	w.OnError = func(err error, _ []serr.SErr) { errs = append(errs, err) }
+
+	var got []Change
+	w.Subscribe(func(_ map[string]map[string]string, changes []Change) { got = changes })
+
+	// replaceFile writes content beside filespec and renames it into place as editors do
+	replaceFile := func(content string) {
+		tmp := filepath.Join(dir, "app.ini.tmp")
+		if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		if err := os.Rename(tmp, filespec); err != nil {
+			t.Fatalf("Failed to rename test file: %v", err)
+		}
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		if w.Poll(context.Background()) {
+			t.Error("Expected no notification")
+		}
+	})
+
+	t.Run("rename replace", func(t *testing.T) {
+		replaceFile("[db]\nhost = b\nuser = u\n")
+		if !w.Poll(context.Background()) {
+			t.Fatal("Expected notification")
+		}
+		expected := []Change{
+			{Key: "db::host", Old: "a", New: "b"},
+			{Key: "db::port", Old: "1", Removed: true},
+			{Key: "db::user", New: "u", Added: true},
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, got)
+		}
+	})
+
+	t.Run("bad file keeps previous config", func(t *testing.T) {
+		replaceFile("host = no section\n")
+		if w.Poll(context.Background()) {
+			t.Error("Expected no notification")
+		}
+		if len(errs) != 1 {
+			t.Errorf("Expected 1 error, got %v", errs)
+		}
+		if w.Current()["db"]["host"] != "b" {
+			t.Errorf("Expected previous config, got %v", w.Current())
+		}
+	})
+
+	t.Run("file with issues keeps previous config", func(t *testing.T) {
+		replaceFile("[db]\nhost = x\nuser = u\n[cache\nttl = 5\n")
+		if w.Poll(context.Background()) {
+			t.Error("Expected no notification")
+		}
+		if len(errs) != 2 {
+			t.Errorf("Expected 2 errors, got %v", errs)
+		}
+		if w.Current()["db"]["host"] != "b" {
+			t.Errorf("Expected previous config, got %v", w.Current())
+		}
+	})
+
+	t.Run("accept issues", func(t *testing.T) {
+		replaceFile("[db]\nhost = b\nuser = u\n")
+		aw, _, err := NewWatcher(filespec)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		aw.Debounce, aw.AcceptIssues = time.Millisecond, true
+		replaceFile("[db]\nhost = x\nuser = u\n[cache\nttl = 5\n")
+		if !aw.Poll(context.Background()) || aw.Current()["db"]["host"] != "x" {
+			t.Errorf("Expected reload with issues to be applied, got %v", aw.Current())
+		}
+	})
+
+	t.Run("rewritten with same values", func(t *testing.T) {
+		replaceFile("[db]\n# comment\nhost = b\nuser = u\n")
+		if w.Poll(context.Background()) {
+			t.Error("Expected no notification")
+		}
+	})
+
+	t.Run("symlink swap", func(t *testing.T) {
+		linkDir := t.TempDir()
+		target1 := filepath.Join(linkDir, "v1.ini")
+		target2 := filepath.Join(linkDir, "v2.ini")
+		link := filepath.Join(linkDir, "app.ini")
+		if err := os.WriteFile(target1, []byte("[db]\nhost = v1\n"), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		if err := os.WriteFile(target2, []byte("[db]\nhost = v2\n"), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		if err := os.Symlink(target1, link); err != nil {
+			t.Skipf("Symlinks not supported: %v", err)
+		}
+
+		lw, _, err := NewWatcher(link)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		lw.Debounce = time.Millisecond
+
+		tmpLink := link + ".tmp"
+		if err := os.Symlink(target2, tmpLink); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+		if err := os.Rename(tmpLink, link); err != nil {
+			t.Fatalf("Failed to swap symlink: %v", err)
+		}
+
+		if !lw.Poll(context.Background()) || lw.Current()["db"]["host"] != "v2" {
+			t.Errorf("Expected reload to v2, got %v", lw.Current())
+		}
+	})
+
+	t.Run("run", func(t *testing.T) {
+		w.Interval = time.Millisecond
+		notified := make(chan []Change, 1)
+		w.Subscribe(func(_ map[string]map[string]string, changes []Change) { notified <- changes })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go w.Run(ctx)
+
+		replaceFile("[db]\nhost = c\nuser = u\n")
+		select {
+		case changes := <-notified:
+			if len(changes) != 1 || changes[0].New != "c" {
+				t.Errorf("Unexpected changes %+v", changes)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for notification")
+		}
+	})
+
+	t.Run("poll while running", func(t *testing.T) {
+		w, _, err := NewWatcher(filespec)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		w.Interval, w.Debounce = time.Millisecond, time.Millisecond
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			w.Run(ctx)
+			close(done)
+		}()
+
+		for i := 0; i < 20; i++ {
+			replaceFile(fmt.Sprintf("[db]\nhost = p%d\nuser = u\n", i))
+			w.Poll(context.Background())
+		}
+		cancel()
+		<-done
+
+		w.Poll(context.Background())
+		if host := w.Current()["db"]["host"]; host != "p19" {
+			t.Errorf("Expected p19, got %s", host)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, _, err := NewWatcher(filepath.Join(dir, "missing.ini")); err == nil {
+			t.Error("Expected error but got none")
+		}
+	})
+}
+
+func TestWatcherEnvFile(t *testing.T) {
+	filespec := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(filespec, []byte("HOST=a\nPORT=1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	t.Setenv("HOST", "untouched")
+
+	w, issues, err := NewWatcher(filespec, EnvLoadFunc)
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("Unexpected err: %v, issues: %v", err, issues)
+	}
+	w.Debounce = time.Millisecond
+	if w.Current()[EnvLoadSection]["HOST"] != "a" {
+		t.Errorf("Expected HOST=a, got %v", w.Current())
+	}
+
+	var got []Change
+	w.Subscribe(func(_ map[string]map[string]string, changes []Change) { got = changes })
+
+	tmp := filespec + ".tmp"
+	if err := os.WriteFile(tmp, []byte("export HOST=b\nPORT=1\nUSER=u\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.Rename(tmp, filespec); err != nil {
+		t.Fatalf("Failed to rename test file: %v", err)
+	}
+
+	if !w.Poll(context.Background()) {
+		t.Fatal("Expected notification")
+	}
+	expected := []Change{
+		{Key: "env::HOST", Old: "a", New: "b"},
+		{Key: "env::USER", New: "u", Added: true},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+	if os.Getenv("HOST") != "untouched" {
+		t.Error("Expected the environment to be left alone")
+	}
+
+	if err := os.WriteFile(tmp, []byte("HOST=\"oops\nPORT=1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.Rename(tmp, filespec); err != nil {
+		t.Fatalf("Failed to rename test file: %v", err)
+	}
+	if w.Poll(context.Background()) {
+		t.Error("Expected no notification for a file with issues")
+	}
+	if w.Current()[EnvLoadSection]["HOST"] != "b" {
+		t.Errorf("Expected previous config, got %v", w.Current())
+	}
+
+	if _, _, err := NewWatcher(filepath.Join(t.TempDir(), "missing.env"), EnvLoadFunc); err == nil {
+		t.Error("Expected error but got none")
+	}
+}
@@ -0,0 +1,74 @@
+package fileops
+
+import (
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/go-serr/serr"
+)
+
+// shellSafe matches values that POSIX shells and ParseEnv both read as written without quotes
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// WriteEnv writes pairs to w as `KEY=value` lines, in the order given, prefixed with `export `
+// if optExport is true. Values are quoted only as needed so that ParseEnv, EnvFromFile and POSIX
+// shells all read back the identical value: single quotes unless the value contains one, then
+// double quotes if the shell won't expand anything inside them. Pairs that can't be written so,
+// such as empty values, invalid names or values with both a single quote and a '$', are skipped
+// and reported as issues. Positions and quotes of pairs are ignored
+func WriteEnv(w io.Writer, pairs []EnvPair, optExport ...bool) (issues []serr.SErr, err error) {
+	prefix := ""
+	if len(optExport) > 0 && optExport[0] {
+		prefix = "export "
+	}
+
+	var sb strings.Builder
+	for _, pair := range pairs {
+		if !posixEnvName.MatchString(pair.Key) {
+			issues = append(issues, serr.NewSErr("Variable name is not POSIX compliant, skipped", "key", pair.Key))
+			continue
+		}
+
+		line, ok := envLine(pair.Key, pair.Value)
+		if !ok {
+			issues = append(issues, serr.NewSErr("Value can't be written so that it reads back unchanged, skipped",
+				"key", pair.Key))
+			continue
+		}
+		sb.WriteString(prefix + line + "\n")
+	}
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return issues, serr.Wrap(err, "Error writing env")
+	}
+	return issues, nil
+}
+
+// envLine returns `key=value`, quoting value as WriteEnv describes, and whether it reads back unchanged
+func envLine(key, val string) (line string, ok bool) {
+	switch {
+	case shellSafe.MatchString(val):
+		line = key + "=" + val
+	case !strings.ContainsRune(val, '\''):
+		line = key + "='" + val + "'"
+	case !strings.ContainsAny(val, "\"$`\\"):
+		line = key + `="` + val + `"`
+	default:
+		return "", false
+	}
+
+	pairs, _, err := ParseEnv(strings.NewReader(line))
+	if err != nil || len(pairs) != 1 || pairs[0].Value != val {
+		return "", false
+	}
+	return line, true
+}
+
+// EnvPairsFromMap returns the pairs of vars sorted by key, for writing with WriteEnv
+func EnvPairsFromMap(vars map[string]string) (pairs []EnvPair) {
+	for _, key := range sortedKeys(vars) {
+		pairs = append(pairs, EnvPair{Key: key, Value: vars[key]})
+	}
+	return
+}
@@ -0,0 +1,111 @@
+package fileops
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteEnv(t *testing.T) {
+	pairs := []EnvPair{
+		{Key: "PLAIN", Value: "postgres://db:5432/app"},
+		{Key: "SPACES", Value: " padded value "},
+		{Key: "HASH", Value: "a#b"},
+		{Key: "DOLLAR", Value: "$HOME and `cmd`"},
+		{Key: "APOSTROPHE", Value: `it's "fine"`},
+		{Key: "BOTH", Value: `it's $HOME`},
+		{Key: "MULTI", Value: "line one  \nline two"},
+		{Key: "EMPTY", Value: ""},
+		{Key: "BAD-NAME", Value: "x"},
+		{Key: "APOSTROPHE2", Value: "it's"},
+	}
+
+	t.Run("quoting", func(t *testing.T) {
+		var buf bytes.Buffer
+		issues, err := WriteEnv(&buf, pairs)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := `PLAIN=postgres://db:5432/app
+SPACES=' padded value '
+HASH='a#b'
+DOLLAR='$HOME and ` + "`cmd`" + `'
+MULTI='line one  
+line two'
+APOSTROPHE2="it's"
+`
+		if buf.String() != expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+		}
+
+		var skipped []string
+		for _, issue := range issues {
+			skipped = append(skipped, issue.FieldsMap()["key"])
+		}
+		if want := []string{"APOSTROPHE", "BOTH", "EMPTY", "BAD-NAME"}; !reflect.DeepEqual(skipped, want) {
+			t.Errorf("Expected %v to be skipped, got %v", want, skipped)
+		}
+	})
+
+	t.Run("reads back", func(t *testing.T) {
+		var buf bytes.Buffer
+		if _, err := WriteEnv(&buf, pairs, true); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.HasPrefix(buf.String(), "export PLAIN=") {
+			t.Errorf("Expected export prefixes, got %s", buf.String())
+		}
+
+		read, _, err := ParseEnv(&buf)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := map[string]string{}
+		for _, pair := range []int{0, 1, 2, 3, 6, 9} {
+			want[pairs[pair].Key] = pairs[pair].Value
+		}
+		got := map[string]string{}
+		for _, pair := range read {
+			got[pair.Key] = pair.Value
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("shell reads back", func(t *testing.T) {
+		sh, err := exec.LookPath("sh")
+		if err != nil {
+			t.Skip("no sh")
+		}
+		var buf bytes.Buffer
+		if _, err := WriteEnv(&buf, pairs, true); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		filespec := filepath.Join(t.TempDir(), "env.sh")
+		if err := os.WriteFile(filespec, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, i := range []int{0, 1, 2, 3, 6, 9} {
+			out, err := exec.Command(sh, "-c", `. "$0" && printf %s "$`+pairs[i].Key+`"`, filespec).Output()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(out) != pairs[i].Value {
+				t.Errorf("Expected %s=%q, got %q", pairs[i].Key, pairs[i].Value, out)
+			}
+		}
+	})
+}
+
+func TestEnvPairsFromMap(t *testing.T) {
+	expected := []EnvPair{{Key: "A", Value: "1"}, {Key: "B", Value: "2"}}
+	if got := EnvPairsFromMap(map[string]string{"B": "2", "A": "1"}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}